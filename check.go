@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	pg "github.com/wiremoons/passgen/lib"
+)
+
+// checkFlags adds the flags used by the 'check' sub command.
+func checkFlags(fs *flag.FlagSet) {
+	fs.StringVar(&breachPath, "breach", "", "\tUSE: '--breach PATH' also look for the password in the Pwned Passwords SHA-1 or NTLM file or range directory at PATH")
+}
+
+// runCheck handles the 'check' sub command, which reads a password from
+// stdin without echoing it, and estimates how hard it is to guess from the
+// patterns it contains. The exit code to use is returned.
+func runCheck(fs *flag.FlagSet) int {
+	var err error
+	if breachPath != "" {
+		if breachCorpus, err = pg.OpenBreachCorpus(breachPath); err != nil {
+			return fail(ioErrorf("unable to open the breach corpus: %s", err))
+		}
+		defer breachCorpus.Close()
+	}
+
+	password, err := readSecret("Password to check: ")
+	if err != nil {
+		return fail(err)
+	}
+	seen := 0
+	if breachCorpus != nil {
+		if seen, err = breachCorpus.Count(password); err != nil {
+			return fail(ioErrorf("unable to search the breach corpus: %s", err))
+		}
+	}
+	strength := pg.EstimateStrength(password)
+
+	fmt.Printf("\n\t\t\tTHREE WORD - PASSWORD STRENGTH\n\t\t\t¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯\n")
+	fmt.Printf("» Password length: %d characters\n", len([]rune(password)))
+	if len([]rune(password)) > pg.MaxStrengthLength {
+		fmt.Printf("\t» only the first %d characters were scored\n", pg.MaxStrengthLength)
+	}
+	fmt.Printf("» Estimated guesses needed: %.3g (about 10^%.1f)\n", strength.Guesses, strength.Log10())
+	if breachCorpus != nil {
+		if seen > 0 {
+			fmt.Printf("» WARNING: found %d times in the %s breach corpus - do not use this password!\n", seen, breachCorpus.Kind())
+			fmt.Printf("\t» attackers try breached passwords first, so the times below are far too long\n")
+		} else {
+			fmt.Printf("» Not found in the %s breach corpus\n", breachCorpus.Kind())
+		}
+	}
+	fmt.Printf("» Patterns found in the password:\n")
+	for _, m := range strength.Matches {
+		detail := ""
+		if m.Detail != "" && m.Detail != m.Token {
+			detail = " - " + m.Detail
+		}
+		fmt.Printf("\t» %-10s  %-12q  %.3g guesses%s\n", m.Pattern, m.Token, m.Guesses, detail)
+	}
+	fmt.Printf("» Estimated time to crack:\n")
+	for _, ct := range strength.CrackTimes() {
+		fmt.Printf("\t» %-52s %s\n", ct.Scenario+":", pg.FormatDuration(ct.Seconds))
+	}
+	fmt.Printf("\nAll is well\n")
+	return exitOK
+}
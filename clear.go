@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// clearAfter is set by '--clear' to the seconds the passwords are shown for
+var clearAfter int
+
+// the longest time accepted for '--clear', in seconds
+const maxClear = 3600
+
+// clearScrollback asks the terminal to also forget its scrollback, for the
+// terminals that do not support the alternate screen
+const clearScrollback = "\x1b[3J"
+
+// showThenClear runs 'output' with its output shown on the alternate screen
+// buffer, so it never reaches the scrollback of the normal screen. After
+// '--clear' seconds, a key press, or SIGINT or SIGTERM, the screen is
+// cleared and the normal screen restored. If 'output' fails the screen is
+// cleared at once, and its errors are shown once the normal screen is back,
+// so they are not cleared with it. The exit code of 'output' is returned,
+// or 128 plus the signal number if a signal was received.
+func showThenClear(output func() int) int {
+	if !isTerminal(os.Stdout) {
+		return fail(usageErrorf("'--clear' needs a terminal to show the passwords on"))
+	}
+
+	// watch for signals first, so the screen is always cleared
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	fmt.Print(altScreenOn + clearScreen)
+	var errs bytes.Buffer
+	errorOutput = &errs
+	code := output()
+	errorOutput = os.Stderr
+	if code != exitOK {
+		fmt.Print(clearScreen + clearScrollback + altScreenOff)
+		os.Stderr.Write(errs.Bytes())
+		return code
+	}
+
+	// wait for a key press too, if there is a terminal to read it from
+	keys := make(chan string, 1)
+	if isTerminal(os.Stdin) {
+		restore, err := rawTerminal()
+		if err == nil {
+			defer restore()
+			go func() {
+				kr := &keyReader{r: os.Stdin}
+				key, _ := kr.readKey()
+				keys <- key
+			}()
+		}
+	}
+
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+wait:
+	for left := clearAfter; left > 0; left-- {
+		fmt.Printf("\r\x1b[K» The screen will be cleared in %d seconds - press any key to clear it now", left)
+		select {
+		case <-keys:
+			break wait
+		case sig := <-signals:
+			if s, ok := sig.(syscall.Signal); ok {
+				code = 128 + int(s)
+			}
+			break wait
+		case <-tick.C:
+		}
+	}
+	fmt.Print(clearScreen + clearScrollback + altScreenOff)
+	return code
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	pg "github.com/wiremoons/passgen/lib"
+)
+
+// copyPassword is set by '--copy' to send the password to the clipboard
+// instead of stdout, and copyClear to the seconds before it is overwritten
+var copyPassword bool
+var copyClear int
+
+// the longest time accepted for '--copy-clear', in seconds
+const maxCopyClear = 3600
+
+// clipboard sets the clipboard of the terminal written to by 'w', using the
+// OSC 52 escape sequence - which works over SSH, where there is no X
+// clipboard. Inside tmux or screen the sequence is wrapped so it is passed
+// on to the terminal. The timer used to clear the clipboard is set by
+// 'after', which is time.After unless replaced.
+type clipboard struct {
+	w     io.Writer
+	wrap  string // "tmux", "screen" or "" for none
+	after func(time.Duration) <-chan time.Time
+}
+
+// newClipboard returns a clipboard that writes to w, wrapping the escape
+// sequences if running inside tmux or screen.
+func newClipboard(w io.Writer) *clipboard {
+	c := &clipboard{w: w, after: time.After}
+	if os.Getenv("TMUX") != "" {
+		c.wrap = "tmux"
+	} else if strings.HasPrefix(os.Getenv("TERM"), "screen") && os.Getenv("STY") != "" {
+		c.wrap = "screen"
+	}
+	return c
+}
+
+// osc52 returns the escape sequence that sets the clipboard to the text.
+// Empty text gives a sequence that overwrites the clipboard with nothing.
+func osc52(text, wrap string) string {
+	seq := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\x07"
+	switch wrap {
+	case "tmux":
+		// tmux passes on sequences in a DCS string, with each escape doubled
+		return "\x1bPtmux;" + strings.Replace(seq, "\x1b", "\x1b\x1b", -1) + "\x1b\\"
+	case "screen":
+		return "\x1bP" + seq + "\x1b\\"
+	}
+	return seq
+}
+
+// copy sets the clipboard to the text.
+func (c *clipboard) copy(text string) error {
+	_, err := io.WriteString(c.w, osc52(text, c.wrap))
+	return err
+}
+
+// clear overwrites the clipboard.
+func (c *clipboard) clear() error {
+	return c.copy("")
+}
+
+// copyFor sets the clipboard to the text, and if 'd' is more than zero
+// waits for that long - or until 'stop' receives - before overwriting it.
+func (c *clipboard) copyFor(text string, d time.Duration, stop <-chan os.Signal) error {
+	if err := c.copy(text); err != nil {
+		return err
+	}
+	if d <= 0 {
+		return nil
+	}
+	select {
+	case <-c.after(d):
+	case <-stop:
+	}
+	return c.clear()
+}
+
+// checkCopy returns an error if '--copy' can not be used with the other
+// options given: there must be just one password to copy.
+func checkCopy() error {
+	if !copyPassword {
+		// a default from the configuration file or environment only applies with '--copy'
+		if copyClear > 0 && settingSources["copy-clear"] == "command line" {
+			return usageErrorf("'--copy-clear' is used with '--copy'")
+		}
+		return nil
+	}
+	if err := checkRange("copy-clear", copyClear, 0, maxCopyClear); err != nil {
+		return err
+	}
+	if !quiet && !interactive {
+		return usageErrorf("'--copy' needs a single password - use it with '-q' or '-i'")
+	}
+	if quiet && quietCount() > 1 {
+		return usageErrorf("'--copy' can only copy one password - do not use '-s' with '-q'")
+	}
+	return nil
+}
+
+// copyToClipboard sends the password to the clipboard of the terminal, and
+// then overwrites it after '--copy-clear' seconds if given, or when
+// SIGINT or SIGTERM is received first. The exit code to use is returned.
+func copyToClipboard(password string) int {
+	var w io.Writer
+	switch {
+	case isTerminal(os.Stdout):
+		w = os.Stdout
+	case isTerminal(os.Stderr):
+		w = os.Stderr
+	default:
+		return fail(usageErrorf("'--copy' needs a terminal to send the password to"))
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	fmt.Fprintf(os.Stderr, "» Password copied to the clipboard\n")
+	if phonetic {
+		fmt.Fprintf(os.Stderr, "» Spelled out: %s\n", pg.Phonetic(password))
+	}
+	if copyClear > 0 {
+		fmt.Fprintf(os.Stderr, "» The clipboard will be cleared in %d seconds - press Ctrl-C to clear it now\n", copyClear)
+	}
+	if err := newClipboard(w).copyFor(password, time.Duration(copyClear)*time.Second, signals); err != nil {
+		return fail(ioErrorf("unable to set the clipboard: %s", err))
+	}
+	if copyClear > 0 {
+		fmt.Fprintf(os.Stderr, "» Clipboard cleared\n")
+	}
+	return exitOK
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestOSC52(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		wrap string
+		want string
+	}{
+		{"plain", "cat-dog-pig", "", "\x1b]52;c;Y2F0LWRvZy1waWc=\x07"},
+		{"plain clear", "", "", "\x1b]52;c;\x07"},
+		{"tmux", "cat-dog-pig", "tmux", "\x1bPtmux;\x1b\x1b]52;c;Y2F0LWRvZy1waWc=\x07\x1b\\"},
+		{"tmux clear", "", "tmux", "\x1bPtmux;\x1b\x1b]52;c;\x07\x1b\\"},
+		{"screen", "cat-dog-pig", "screen", "\x1bP\x1b]52;c;Y2F0LWRvZy1waWc=\x07\x1b\\"},
+		{"screen clear", "", "screen", "\x1bP\x1b]52;c;\x07\x1b\\"},
+	}
+	for _, tt := range tests {
+		if got := osc52(tt.text, tt.wrap); got != tt.want {
+			t.Errorf("%s: osc52(%q, %q) = %q, want %q", tt.name, tt.text, tt.wrap, got, tt.want)
+		}
+	}
+}
+
+// fakeTimer replaces time.After for a clipboard, recording the duration
+// asked for and returning a channel the test controls.
+type fakeTimer struct {
+	asked []time.Duration
+	fire  chan time.Time
+}
+
+func (f *fakeTimer) after(d time.Duration) <-chan time.Time {
+	f.asked = append(f.asked, d)
+	return f.fire
+}
+
+func TestCopyFor(t *testing.T) {
+	copied := osc52("cat-dog-pig", "")
+	cleared := osc52("", "")
+	tests := []struct {
+		name   string
+		d      time.Duration
+		timer  bool // the timer fires
+		signal bool // a signal is received on stop
+		want   string
+		asked  int
+	}{
+		{"no clear", 0, false, false, copied, 0},
+		{"timer", 30 * time.Second, true, false, copied + cleared, 1},
+		{"stop signal", 30 * time.Second, false, true, copied + cleared, 1},
+	}
+	for _, tt := range tests {
+		var w bytes.Buffer
+		timer := &fakeTimer{fire: make(chan time.Time, 1)}
+		stop := make(chan os.Signal, 1)
+		if tt.timer {
+			timer.fire <- time.Now()
+		}
+		if tt.signal {
+			stop <- syscall.SIGINT
+		}
+		c := &clipboard{w: &w, after: timer.after}
+		if err := c.copyFor("cat-dog-pig", tt.d, stop); err != nil {
+			t.Fatalf("%s: copyFor returned %v", tt.name, err)
+		}
+		if got := w.String(); got != tt.want {
+			t.Errorf("%s: wrote %q, want %q", tt.name, got, tt.want)
+		}
+		if len(timer.asked) != tt.asked {
+			t.Errorf("%s: timer set %d times, want %d", tt.name, len(timer.asked), tt.asked)
+		} else if tt.asked > 0 && timer.asked[0] != tt.d {
+			t.Errorf("%s: timer set for %v, want %v", tt.name, timer.asked[0], tt.d)
+		}
+	}
+}
+
+func TestCopyForWrapped(t *testing.T) {
+	var w bytes.Buffer
+	timer := &fakeTimer{fire: make(chan time.Time, 1)}
+	timer.fire <- time.Now()
+	c := &clipboard{w: &w, wrap: "tmux", after: timer.after}
+	if err := c.copyFor("cat", time.Second, nil); err != nil {
+		t.Fatalf("copyFor returned %v", err)
+	}
+	if want := osc52("cat", "tmux") + osc52("", "tmux"); w.String() != want {
+		t.Errorf("wrote %q, want %q", w.String(), want)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"unicode"
+)
+
+// colourMode is set by '--colour' to choose when passwords are coloured:
+// "auto", "always" or "never". colourOn records the choice made for this
+// run, once the terminal has been checked.
+var colourMode = "auto"
+var colourOn bool
+
+// ANSI escape sequences used to colour the parts of a password
+const (
+	colourUpper  = "\x1b[1;33m" // bold yellow
+	colourDigit  = "\x1b[1;36m" // bold cyan
+	colourSymbol = "\x1b[1;35m" // bold magenta
+	colourWord   = "\x1b[4m"    // underlined, for every other word
+)
+
+// checkColour checks the value given with '--colour', and decides if
+// passwords are coloured. With "auto" they are only coloured when stdout is
+// a terminal, the NO_COLOR environment variable is not set, and the
+// terminal is not a dumb one.
+func checkColour() error {
+	switch colourMode {
+	case "always":
+		colourOn = true
+	case "never":
+		colourOn = false
+	case "auto":
+		colourOn = isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb"
+	default:
+		return usageErrorf("'--colour' must be one of 'auto', 'always' or 'never' - not '%s'", colourMode)
+	}
+	return nil
+}
+
+// paint returns the password coloured to show its parts, if colour is on:
+// upper case letters, digits and symbols each have their own colour, and
+// every other word is underlined so the words can be told apart when there
+// are no spaces between them.
+func paint(password string) string {
+	if !colourOn {
+		return password
+	}
+	var b strings.Builder
+	current := ""
+	for i, word := range paintWords(password) {
+		for _, r := range word {
+			style := ""
+			switch {
+			case unicode.IsUpper(r):
+				style = colourUpper
+			case unicode.IsDigit(r):
+				style = colourDigit
+			case !unicode.IsLetter(r) && r != ' ':
+				style = colourSymbol
+			}
+			if i%2 == 1 && !isSeparator(r) {
+				style += colourWord
+			}
+			// the escape sequences are only written when the style changes
+			if style != current {
+				if current != "" {
+					b.WriteString(resetStyle)
+				}
+				b.WriteString(style)
+				current = style
+			}
+			b.WriteRune(r)
+		}
+	}
+	if current != "" {
+		b.WriteString(resetStyle)
+	}
+	return b.String()
+}
+
+// paintWords splits a password into its words, keeping the separators,
+// digits and symbols that follow a word at the end of it, so the words
+// joined together give the password. The words are found from the letters:
+// a run of letters ends a word at the next space, separator, digit or
+// symbol - as policy passwords put digits and symbols between the words -
+// and a run of letters that could be three letter words run together, such
+// as 'catdogpig' from '-r', is split into those words.
+func paintWords(password string) []string {
+	var words []string
+	runes := []rune(password)
+	for i := 0; i < len(runes); {
+		// the run of letters, and then the run of anything else after it
+		letters := i
+		for letters < len(runes) && unicode.IsLetter(runes[letters]) {
+			letters++
+		}
+		end := letters
+		for end < len(runes) && !unicode.IsLetter(runes[end]) {
+			end++
+		}
+		if n := letters - i; n > 3 && n%3 == 0 {
+			for ; i+3 < letters; i += 3 {
+				words = append(words, string(runes[i:i+3]))
+			}
+		}
+		words = append(words, string(runes[i:end]))
+		i = end
+	}
+	return words
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPaintWords(t *testing.T) {
+	tests := []struct {
+		password string
+		want     []string
+	}{
+		{"cat dog pig", []string{"cat ", "dog ", "pig"}},
+		{"catdogpig", []string{"cat", "dog", "pig"}},
+		{"CaTdOGpig", []string{"CaT", "dOG", "pig"}},
+		{"cat-dog.pig", []string{"cat-", "dog.", "pig"}},
+		// policy passwords, with digits and symbols between the words
+		{"ado7auf2one7gid", []string{"ado7", "auf2", "one7", "gid"}},
+		{"Ado7auf-oneGid!4", []string{"Ado7", "auf-", "one", "Gid!4"}},
+		{"catdog42", []string{"cat", "dog42"}},
+		// letters that are not three letter words run together stay whole
+		{"hello world", []string{"hello ", "world"}},
+		{"7up", []string{"7", "up"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		got := paintWords(tt.password)
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("paintWords(%q) = %q, want %q", tt.password, got, tt.want)
+		}
+		if strings.Join(got, "") != tt.password {
+			t.Errorf("paintWords(%q) words do not join to the password: %q", tt.password, got)
+		}
+	}
+}
+
+func TestPaint(t *testing.T) {
+	defer func(on bool) { colourOn = on }(colourOn)
+	colourOn = true
+	tests := []struct {
+		password string
+		want     string
+	}{
+		{"catdogpig", "cat" + colourWord + "dog" + resetStyle + "pig"},
+		{"cat dog", "cat " + colourWord + "dog" + resetStyle},
+		{"ado7auf", "ado" + colourDigit + "7" + resetStyle + colourWord + "auf" + resetStyle},
+	}
+	for _, tt := range tests {
+		if got := paint(tt.password); got != tt.want {
+			t.Errorf("paint(%q) = %q, want %q", tt.password, got, tt.want)
+		}
+	}
+}
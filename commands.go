@@ -0,0 +1,268 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+
+	pg "github.com/wiremoons/passgen/lib"
+)
+
+// command describes one of the sub commands of the application, such as
+// 'generate' or 'check'. The flags used by the command are added to its
+// flag set by 'flags', and once they are parsed 'run' is called, which
+// returns the exit code to use.
+type command struct {
+	name    string                     // name used on the command line
+	args    string                     // arguments shown in the usage line
+	maxArgs int                        // most arguments allowed after the flags
+	summary string                     // one line description of the command
+	flags   func(fs *flag.FlagSet)     // adds the command's flags
+	run     func(fs *flag.FlagSet) int // runs the command
+}
+
+// commands holds every sub command, in the order shown in the help text.
+var commands []*command
+
+// init function sets up the list of sub commands - this can not be done
+// when 'commands' is declared, as the help command refers to the list.
+func init() {
+	commands = []*command{
+		{"generate", "[options]", 0, "generate password suggestions (the default when no command is given)", generateFlags, runGenerate},
+		{"check", "[options] < password", 0, "estimate the strength of a password read from stdin", checkFlags, runCheck},
+		{"verify", "--policy NAME [options] < password", 0, "check a password read from stdin meets a password policy", verifyFlags, runVerify},
+		{"wifi", "--ssid NAME [options]", 0, "generate a Wi-Fi passphrase, with the join string and QR code for phones", wifiFlags, runWifi},
+		{"sheet", "--names NAME,NAME [options]", 0, "make a printable HTML or SVG sheet of passwords for a list of names", sheetFlags, runSheet},
+		{"provision", "--in users.csv [options]", 0, "make a password for each user in a CSV file, and output them as CSV", provisionFlags, runProvision},
+		{"practice", "[options]", 0, "practise typing a password from memory, to help remember it", practiceFlags, runPractice},
+		{"list", "[options] [policies|words]", 1, "list the password policies or the three letter words available", listFlags, runList},
+		{"stats", "[options]", 0, "show the number of possible passwords and their strength", statsFlags, runStats},
+		{"config", "[options] [show]", 1, "show the settings in use, and where each one came from", generateFlags, runConfig},
+		{"completion", "bash|zsh|fish", 1, "output a shell completion script for bash, zsh or fish", nil, runCompletion},
+		{"docs", "--man|--markdown", 0, "output a man page or a Markdown reference document", docsFlags, runDocs},
+		{"version", "[options]", 0, "display the application version, build details and word list fingerprint", versionFlags, runVersion},
+		{"help", "[command]", 1, "display help about the application or one of its commands", nil, runHelp},
+	}
+}
+
+// findCommand returns the sub command with the name given, or nil if there
+// is no such command.
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// execute parses the arguments given for the command, and then runs it.
+// The exit code to use is returned.
+func (c *command) execute(args []string) int {
+	fs := c.flagSet()
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() > c.maxArgs {
+		return fail(usageErrorf("unexpected argument '%s' - run '%s help %s' for help", fs.Arg(c.maxArgs), appname, c.name))
+	}
+	if err := applySettings(c, fs); err != nil {
+		return fail(err)
+	}
+	return c.run(fs)
+}
+
+// flagSet returns a new flag set holding the command's flags.
+func (c *command) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(appname+" "+c.name, flag.ContinueOnError)
+	if c.flags != nil {
+		c.flags(fs)
+	}
+	fs.Usage = func() { c.usage(fs.Output(), fs) }
+	return fs
+}
+
+// usage writes the usage line, description and flags of the command.
+func (c *command) usage(w io.Writer, fs *flag.FlagSet) {
+	fmt.Fprintf(w, "Usage: %s %s %s\n\n", appname, c.name, c.args)
+	fmt.Fprintf(w, "  %s\n", c.summary)
+	hasFlags := false
+	fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+	if hasFlags {
+		fmt.Fprintf(w, "\nOptions:\n")
+		fs.SetOutput(w)
+		fs.PrintDefaults()
+	}
+}
+
+// generateFlags adds the flags used to choose the passwords generated.
+// They are shared by every command that generates passwords.
+func generateFlags(fs *flag.FlagSet) {
+	// IntVar; StringVar; BoolVar options for flag
+	// format required: variable, cmd line flag, initial value, description.
+	fs.BoolVar(&passcase, "c", false, "\tUSE: '-c' provide mixed case passwords [DEFAULT: lowercase]")
+	fs.BoolVar(&helpMe, "h", false, "\tUSE: '-h' display more detailed help about this program - same as the 'help' command")
+	fs.BoolVar(&interactive, "i", false, "\tUSE: '-i' choose and refine a password in an interactive screen [DEFAULT: list the suggestions]")
+	fs.BoolVar(&quiet, "q", false, "\tUSE: '-q' to obtain just ONE password, or the number given with '-s', one per line - no other screen output [DEFAULT: additional info output]")
+	fs.BoolVar(&remove, "r", false, "\tUSE: '-r' remove password spaces [DEFAULT: with spaces]")
+	fs.IntVar(&numsuggestions, "s", 3, fmt.Sprintf("\tUSE: '-s #' where # is the number of password suggestions offered, from 1 to %d [DEFAULT: 3]", maxSuggestions))
+	fs.BoolVar(&version, "v", false, "\tUSE: '-v' display the application version - same as the 'version' command")
+	fs.IntVar(&numwords, "w", 3, fmt.Sprintf("\tUSE: '-w #' where # is the number of three letter words to use, from 1 to %d [DEFAULT: 3]", maxWords))
+	fs.BoolVar(&phonetic, "phonetic", false, "\tUSE: '--phonetic' spell out each password with the NATO phonetic alphabet, marking upper case, digits and symbols")
+	fs.BoolVar(&qrShow, "qr", false, "\tUSE: '--qr' with '-q' or '-i' also show the password as a QR code, to scan with a phone")
+	fs.StringVar(&qrFile, "qr-file", "", "\tUSE: '--qr-file PATH' with '-q' or '-i' write the password as a QR code to the PNG or SVG file PATH, readable only by you")
+	fs.BoolVar(&unique, "unique", false, "\tUSE: '--unique' never output the same password twice in one run [DEFAULT: repeats are possible]")
+	fs.BoolVar(&copyPassword, "copy", false, "\tUSE: '--copy' with '-q' or '-i' send the password to the terminal's clipboard (OSC 52) instead of stdout")
+	fs.IntVar(&copyClear, "copy-clear", 0, fmt.Sprintf("\tUSE: '--copy-clear #' overwrite the clipboard after # seconds (1 to %d) [DEFAULT: never]", maxCopyClear))
+	nonTTYFlags(fs)
+	colourFlags(fs)
+	fs.StringVar(&outPath, "out", "", "\tUSE: '--out PATH' write the passwords to the file PATH, readable only by you, instead of stdout")
+	fs.StringVar(&outNames, "names", "", "\tUSE: '--names NAME,NAME' with '--out DIR' write one password per name, each to its own file in DIR")
+	fs.BoolVar(&outForce, "force", false, "\tUSE: '--force' with '--out' or '--qr-file' replace files that already exist [DEFAULT: refuse]")
+	fs.IntVar(&clearAfter, "clear", 0, fmt.Sprintf("\tUSE: '--clear #' show the passwords on the alternate screen, and clear them after # seconds (1 to %d) or a key press [DEFAULT: no clearing]", maxClear))
+	hashFlags(fs)
+	fs.StringVar(&breachPath, "breach", "", "\tUSE: '--breach PATH' reject passwords found in the Pwned Passwords SHA-1 or NTLM file or range directory at PATH")
+	policyFlags(fs)
+}
+
+// nonTTYFlags adds the flags that choose what happens when passwords are
+// written to stdout and it is not a terminal.
+func nonTTYFlags(fs *flag.FlagSet) {
+	fs.StringVar(&nonTTY, "non-tty", "warn", "\tUSE: '--non-tty warn|refuse|allow' what to do when passwords are written to stdout that is not a terminal [DEFAULT: warn]")
+	fs.BoolVar(&pipeOK, "pipe", false, "\tUSE: '--pipe' allow passwords to be written to a file or pipe on stdout, for pipelines")
+}
+
+// colourFlags adds the flag that chooses when passwords are coloured.
+func colourFlags(fs *flag.FlagSet) {
+	fs.StringVar(&colourMode, "colour", "auto", "\tUSE: '--colour auto|always|never' colour upper case letters, digits, symbols and every other word of each password [DEFAULT: auto]")
+}
+
+// policyFlags adds the flags used to choose a password policy.
+func policyFlags(fs *flag.FlagSet) {
+	fs.StringVar(&policyName, "policy", "", "\tUSE: '--policy NAME' use the named policy from the policy catalogue")
+	fs.StringVar(&policyFile, "policy-file", "", "\tUSE: '--policy-file PATH' also load named policies from the catalogue file PATH [DEFAULT: "+pg.UserPolicyPath()+"]")
+	fs.Var(&pwquality, "pwquality", "\tUSE: '--pwquality' or '--pwquality=PATH' use the pam_pwquality rules in PATH [DEFAULT: "+pg.PWQualityPath+"]")
+}
+
+// runHelp handles the 'help' sub command. With no arguments the detailed
+// help text is shown, followed by the commands and the options used to
+// generate passwords. Given the name of a command, its usage is shown.
+func runHelp(fs *flag.FlagSet) int {
+	if fs.NArg() > 0 {
+		cmd := findCommand(fs.Arg(0))
+		if cmd == nil {
+			return fail(usageErrorf("unknown command '%s' - run '%s help' to see the commands available", fs.Arg(0), appname))
+		}
+		cmdfs := cmd.flagSet()
+		cmd.usage(os.Stdout, cmdfs)
+		return exitOK
+	}
+	// call function to display information about the application
+	pg.PrintHelp()
+	fmt.Printf("Usage: %s [command] [options]\n\nCommands:\n", appname)
+	for _, cmd := range commands {
+		fmt.Printf("  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Printf("\nRun '%s help COMMAND' for the options used by each command.\n", appname)
+	fmt.Printf("The options below are used when no command is given, or with 'generate':\n\n")
+	gen := findCommand("generate").flagSet()
+	gen.SetOutput(os.Stdout)
+	gen.PrintDefaults()
+	fmt.Printf("\nExit codes:\n")
+	for _, e := range exitCodes {
+		fmt.Printf("  %d  %s\n", e.code, e.desc)
+	}
+	// let user know we ran as expected
+	fmt.Printf("\n\nAll is well.\n\n")
+	return exitOK
+}
+
+// listFlags adds the flags used by the 'list' sub command.
+func listFlags(fs *flag.FlagSet) {
+	fs.StringVar(&policyFile, "policy-file", "", "\tUSE: '--policy-file PATH' also load named policies from the catalogue file PATH")
+}
+
+// runList handles the 'list' sub command, which outputs the names and
+// descriptions of the policies in the policy catalogue, or the words in
+// the three letter word pool - one per line.
+func runList(fs *flag.FlagSet) int {
+	what := "policies"
+	if fs.NArg() > 0 {
+		what = fs.Arg(0)
+	}
+	switch what {
+	case "policies":
+		var files []string
+		if policyFile != "" {
+			files = append(files, policyFile)
+		}
+		catalogue, err := pg.LoadCatalogue(files...)
+		if err != nil {
+			return fail(ioErrorf("unable to load the policy catalogue: %s", err))
+		}
+		for _, name := range catalogue.Names() {
+			fmt.Printf("%-16s %s\n", name, catalogue[name].Description)
+		}
+	case "words":
+		words := make([]string, 0, len(pg.Passmap))
+		for _, word := range pg.Passmap {
+			words = append(words, word)
+		}
+		sort.Strings(words)
+		for _, word := range words {
+			fmt.Println(word)
+		}
+	default:
+		return fail(usageErrorf("unable to list '%s' - choose 'policies' or 'words'", what))
+	}
+	return exitOK
+}
+
+// statsFlags adds the flags used by the 'stats' sub command.
+func statsFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&passcase, "c", false, "\tUSE: '-c' mixed case passwords [DEFAULT: lowercase]")
+	fs.IntVar(&numwords, "w", 3, fmt.Sprintf("\tUSE: '-w #' where # is the number of three letter words to use, from 1 to %d [DEFAULT: 3]", maxWords))
+	policyFlags(fs)
+}
+
+// runStats handles the 'stats' sub command, which shows how many passwords
+// could be generated with the options given, and how long it would take to
+// guess one of them.
+func runStats(fs *flag.FlagSet) int {
+	if err := checkRange("w", numwords, 1, maxWords); err != nil {
+		return fail(err)
+	}
+	plan := pg.Plan{Words: numwords, Mixed: passcase}
+	policy, ok, err := selectPolicy()
+	if err != nil {
+		return fail(err)
+	}
+	if ok {
+		if plan, err = planPolicy(policy); err != nil {
+			return fail(err)
+		}
+	}
+	bits := plan.Entropy()
+
+	fmt.Printf("\n\t\t\tTHREE WORD - PASSWORD STATISTICS\n\t\t\t¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯\n")
+	fmt.Printf("» Number of three letter words available in the pool is: %d\n", len(pg.Passmap))
+	fmt.Printf("\t» Entropy of each randomly chosen word: %.2f bits\n", math.Log2(float64(len(pg.Passmap))))
+	if ok {
+		fmt.Printf("» Password policy: %s\n", policy.Name)
+	}
+	fmt.Printf("» Passwords of %d words, mixed case: %t, digits: %d, symbols: %d\n", plan.Words, plan.Mixed, plan.Digits, plan.Symbols)
+	fmt.Printf("\t» Password character length will therefore be: %d\n", plan.Length())
+	fmt.Printf("» Possible passwords: %.3g (%.1f bits of entropy)\n", math.Pow(2, bits), bits)
+	fmt.Printf("» Estimated time to guess one, knowing how it was generated:\n")
+	for _, ct := range (pg.Strength{Guesses: math.Pow(2, bits)}).CrackTimes() {
+		fmt.Printf("\t» %-52s %s\n", ct.Scenario+":", pg.FormatDuration(ct.Seconds))
+	}
+	fmt.Printf("\nAll is well\n")
+	return exitOK
+}
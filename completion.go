@@ -0,0 +1,336 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// completionValues holds the kind of value taken by each flag that is not a
+// simple on/off flag, where it can be completed: 'policy' for the names in
+// the policy catalogue, or 'file' for a file name. Other flags taking a
+// value, such as '-w', have nothing to complete.
+var completionValues = map[string]string{
+	"policy":      "policy",
+	"policy-file": "file",
+	"breach":      "file",
+	"out":         "file",
+	"qr-file":     "file",
+	"in":          "file",
+}
+
+// completionFlag describes one flag of a command for completion.
+type completionFlag struct {
+	name    string   // name without its leading dashes
+	desc    string   // short description, from the usage string
+	value   string   // "" for on/off flags, "policy", "file", "choice" or "value"
+	choices []string // the values of a "choice" flag
+}
+
+// option returns the flag as it is usually typed.
+func (f completionFlag) option() string {
+	return docOption(f.name)
+}
+
+// completionFlags returns the flags used by the command.
+func completionFlags(cmd *command) []completionFlag {
+	var flags []completionFlag
+	cmd.flagSet().VisitAll(func(f *flag.Flag) {
+		cf := completionFlag{name: f.Name, desc: flagSummary(f)}
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); !ok || !b.IsBoolFlag() {
+			cf.value = "value"
+			if kind, ok := completionValues[f.Name]; ok {
+				cf.value = kind
+			}
+			if choices := completionChoices(f.Name); choices != nil {
+				cf.value, cf.choices = "choice", choices
+			}
+		}
+		flags = append(flags, cf)
+	})
+	return flags
+}
+
+// completionArgs returns the arguments that can follow the flags of the
+// command.
+func completionArgs(cmd *command) []string {
+	switch cmd.name {
+	case "help":
+		var names []string
+		for _, c := range commands {
+			names = append(names, c.name)
+		}
+		return names
+	case "list":
+		return []string{"policies", "words"}
+	case "config":
+		return []string{"show"}
+	case "completion":
+		return []string{"bash", "zsh", "fish"}
+	}
+	return nil
+}
+
+// completionChoices returns the values that can be given to a flag that
+// takes one of a fixed set of values, or nil for other flags.
+func completionChoices(name string) []string {
+	switch name {
+	case "non-tty":
+		return []string{"warn", "refuse", "allow"}
+	case "hash":
+		return hashNames()
+	case "colour":
+		return []string{"auto", "always", "never"}
+	}
+	return nil
+}
+
+// flagSummary shortens the usage string of a flag, such as "\tUSE: '-w #'
+// where # is the number of ... [DEFAULT: 3]", to a description fit for
+// completion menus.
+func flagSummary(f *flag.Flag) string {
+	_, s := flagDoc(f)
+	s = strings.TrimPrefix(s, "where # is ")
+	if i := strings.Index(s, " ["); i >= 0 {
+		s = s[:i]
+	}
+	return s
+}
+
+// shellQuote returns the string quoted for use in a shell script.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// completionName returns the application name made safe for use as part of
+// a shell function name.
+func completionName() string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, appname)
+}
+
+// runCompletion handles the 'completion' sub command, which outputs a
+// completion script for bash, zsh or fish. The policy names are found when
+// completing, by running '<app> list policies', so policies added to the
+// catalogue later are included.
+func runCompletion(fs *flag.FlagSet) int {
+	if fs.NArg() == 0 {
+		return fail(usageErrorf("choose the shell to output a completion script for: bash, zsh or fish"))
+	}
+	switch fs.Arg(0) {
+	case "bash":
+		bashCompletion(os.Stdout)
+	case "zsh":
+		zshCompletion(os.Stdout)
+	case "fish":
+		fishCompletion(os.Stdout)
+	default:
+		return fail(usageErrorf("unable to output completion for '%s' - choose bash, zsh or fish", fs.Arg(0)))
+	}
+	return exitOK
+}
+
+// bashCompletion writes the bash completion script. Load it with:
+// source <(passgen completion bash)
+func bashCompletion(w io.Writer) {
+	fn := "_" + completionName()
+	var names []string
+	for _, cmd := range commands {
+		names = append(names, cmd.name)
+	}
+	var policy, file, value, choice []string
+	choices := map[string][]string{}
+	for _, cmd := range commands {
+		for _, f := range completionFlags(cmd) {
+			both := "-" + f.name + "|--" + f.name
+			switch f.value {
+			case "choice":
+				if _, ok := choices[both]; !ok {
+					choice = append(choice, both)
+				}
+				choices[both] = f.choices
+			case "policy":
+				policy = appendOnce(policy, both)
+			case "file":
+				file = appendOnce(file, both)
+			case "value":
+				value = appendOnce(value, both)
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "# bash completion for %s - generated by '%s completion bash'\n", appname, appname)
+	fmt.Fprintf(w, "# load with: source <(%s completion bash)\n", appname)
+	fmt.Fprintf(w, "%s() {\n", fn)
+	fmt.Fprintf(w, "    local cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	fmt.Fprintf(w, "    local cmd=generate opts args\n")
+	fmt.Fprintf(w, "    if [[ $COMP_CWORD -gt 1 && ${COMP_WORDS[1]} != -* ]]; then\n")
+	fmt.Fprintf(w, "        cmd=\"${COMP_WORDS[1]}\"\n")
+	fmt.Fprintf(w, "    fi\n")
+	fmt.Fprintf(w, "    case \"$prev\" in\n")
+	if len(policy) > 0 {
+		fmt.Fprintf(w, "        %s)\n", strings.Join(policy, "|"))
+		fmt.Fprintf(w, "            COMPREPLY=($(compgen -W \"$(\"${COMP_WORDS[0]}\" list policies 2>/dev/null | cut -d' ' -f1)\" -- \"$cur\"))\n")
+		fmt.Fprintf(w, "            return ;;\n")
+	}
+	if len(file) > 0 {
+		fmt.Fprintf(w, "        %s)\n", strings.Join(file, "|"))
+		fmt.Fprintf(w, "            COMPREPLY=($(compgen -f -- \"$cur\"))\n")
+		fmt.Fprintf(w, "            return ;;\n")
+	}
+	for _, both := range choice {
+		fmt.Fprintf(w, "        %s)\n", both)
+		fmt.Fprintf(w, "            COMPREPLY=($(compgen -W %s -- \"$cur\"))\n", shellQuote(strings.Join(choices[both], " ")))
+		fmt.Fprintf(w, "            return ;;\n")
+	}
+	if len(value) > 0 {
+		fmt.Fprintf(w, "        %s)\n", strings.Join(value, "|"))
+		fmt.Fprintf(w, "            return ;;\n")
+	}
+	fmt.Fprintf(w, "    esac\n")
+	fmt.Fprintf(w, "    case \"$cmd\" in\n")
+	for _, cmd := range commands {
+		var opts []string
+		for _, f := range completionFlags(cmd) {
+			opts = append(opts, f.option())
+		}
+		fmt.Fprintf(w, "        %s) opts=%s; args=%s ;;\n", cmd.name, shellQuote(strings.Join(opts, " ")), shellQuote(strings.Join(completionArgs(cmd), " ")))
+	}
+	fmt.Fprintf(w, "    esac\n")
+	fmt.Fprintf(w, "    if [[ $cur == -* ]]; then\n")
+	fmt.Fprintf(w, "        COMPREPLY=($(compgen -W \"$opts\" -- \"$cur\"))\n")
+	fmt.Fprintf(w, "    elif [[ $COMP_CWORD -eq 1 ]]; then\n")
+	fmt.Fprintf(w, "        COMPREPLY=($(compgen -W %s -- \"$cur\"))\n", shellQuote(strings.Join(names, " ")))
+	fmt.Fprintf(w, "    else\n")
+	fmt.Fprintf(w, "        COMPREPLY=($(compgen -W \"$args\" -- \"$cur\"))\n")
+	fmt.Fprintf(w, "    fi\n")
+	fmt.Fprintf(w, "}\n")
+	fmt.Fprintf(w, "complete -F %s %s\n", fn, appname)
+}
+
+// zshCompletion writes the zsh completion script. Load it with:
+// source <(passgen completion zsh), or save it as '_passgen' in a
+// directory in $fpath.
+func zshCompletion(w io.Writer) {
+	fn := "_" + completionName()
+	fmt.Fprintf(w, "#compdef %s\n", appname)
+	fmt.Fprintf(w, "# zsh completion for %s - generated by '%s completion zsh'\n\n", appname, appname)
+	fmt.Fprintf(w, "%s_policies() {\n", fn)
+	fmt.Fprintf(w, "    local -a policies\n")
+	fmt.Fprintf(w, "    policies=(${(f)\"$(\"$%s_prog\" list policies 2>/dev/null | sed -e 's/:/\\\\:/g' -e 's/  */:/')\"})\n", fn)
+	fmt.Fprintf(w, "    _describe -t policies 'password policy' policies\n")
+	fmt.Fprintf(w, "}\n\n")
+	fmt.Fprintf(w, "%s() {\n", fn)
+	fmt.Fprintf(w, "    local cmd=generate %s_prog=$words[1]\n", fn)
+	fmt.Fprintf(w, "    local -a commands\n")
+	fmt.Fprintf(w, "    commands=(\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "        %s\n", shellQuote(cmd.name+":"+strings.Replace(cmd.summary, ":", `\:`, -1)))
+	}
+	fmt.Fprintf(w, "    )\n")
+	fmt.Fprintf(w, "    if (( CURRENT == 2 )) && [[ $words[2] != -* ]]; then\n")
+	fmt.Fprintf(w, "        _describe -t commands '%s command' commands\n", appname)
+	fmt.Fprintf(w, "        return\n")
+	fmt.Fprintf(w, "    fi\n")
+	fmt.Fprintf(w, "    if [[ $words[2] != -* ]]; then\n")
+	fmt.Fprintf(w, "        cmd=$words[2]\n")
+	fmt.Fprintf(w, "        shift words\n")
+	fmt.Fprintf(w, "        (( CURRENT-- ))\n")
+	fmt.Fprintf(w, "    fi\n")
+	fmt.Fprintf(w, "    case $cmd in\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "        %s)\n", cmd.name)
+		fmt.Fprintf(w, "            _arguments -s")
+		for _, f := range completionFlags(cmd) {
+			desc := strings.NewReplacer(`[`, `\[`, `]`, `\]`, `:`, `\:`).Replace(f.desc)
+			spec := f.option() + "[" + desc + "]"
+			switch f.value {
+			case "policy":
+				spec += ":policy:" + fn + "_policies"
+			case "file":
+				spec += ":path:_files"
+			case "choice":
+				spec += ":value:(" + strings.Join(f.choices, " ") + ")"
+			case "value":
+				spec += ":value: "
+			}
+			fmt.Fprintf(w, " \\\n                %s", shellQuote(spec))
+		}
+		if args := completionArgs(cmd); len(args) > 0 {
+			fmt.Fprintf(w, " \\\n                %s", shellQuote("1:argument:("+strings.Join(args, " ")+")"))
+		}
+		fmt.Fprintf(w, " ;;\n")
+	}
+	fmt.Fprintf(w, "    esac\n")
+	fmt.Fprintf(w, "}\n\n")
+	fmt.Fprintf(w, "compdef %s %s\n", fn, appname)
+}
+
+// fishCompletion writes the fish completion script. Load it with:
+// passgen completion fish | source, or save it as 'passgen.fish' in
+// ~/.config/fish/completions.
+func fishCompletion(w io.Writer) {
+	fn := "__" + completionName()
+	fmt.Fprintf(w, "# fish completion for %s - generated by '%s completion fish'\n\n", appname, appname)
+	fmt.Fprintf(w, "function %s_command\n", fn)
+	fmt.Fprintf(w, "    set -l words (commandline -opc)\n")
+	fmt.Fprintf(w, "    if test (count $words) -ge 2; and not string match -q -- '-*' $words[2]\n")
+	fmt.Fprintf(w, "        echo $words[2]\n")
+	fmt.Fprintf(w, "    else\n")
+	fmt.Fprintf(w, "        echo generate\n")
+	fmt.Fprintf(w, "    end\n")
+	fmt.Fprintf(w, "end\n\n")
+	fmt.Fprintf(w, "function %s_using\n", fn)
+	fmt.Fprintf(w, "    test (%s_command) = $argv[1]\n", fn)
+	fmt.Fprintf(w, "end\n\n")
+	fmt.Fprintf(w, "function %s_needs_command\n", fn)
+	fmt.Fprintf(w, "    test (count (commandline -opc)) -eq 1\n")
+	fmt.Fprintf(w, "end\n\n")
+	fmt.Fprintf(w, "function %s_policies\n", fn)
+	fmt.Fprintf(w, "    set -l words (commandline -opc)\n")
+	fmt.Fprintf(w, "    $words[1] list policies 2>/dev/null | string replace -r '\\s+' \\t\n")
+	fmt.Fprintf(w, "end\n\n")
+	fmt.Fprintf(w, "complete -c %s -f\n", appname)
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "complete -c %s -n %s_needs_command -a %s -d %s\n", appname, fn, cmd.name, shellQuote(cmd.summary))
+	}
+	for _, cmd := range commands {
+		cond := shellQuote(fn + "_using " + cmd.name)
+		for _, f := range completionFlags(cmd) {
+			opt := "-l " + f.name
+			if len(f.name) == 1 {
+				opt = "-s " + f.name
+			}
+			switch f.value {
+			case "policy":
+				opt += " -x -a '(" + fn + "_policies)'"
+			case "file":
+				opt += " -r -F"
+			case "choice":
+				opt += " -x -a " + shellQuote(strings.Join(f.choices, " "))
+			case "value":
+				opt += " -x"
+			}
+			fmt.Fprintf(w, "complete -c %s -n %s %s -d %s\n", appname, cond, opt, shellQuote(f.desc))
+		}
+		if args := completionArgs(cmd); len(args) > 0 {
+			fmt.Fprintf(w, "complete -c %s -n %s -a %s\n", appname, shellQuote("not "+fn+"_needs_command; and "+fn+"_using "+cmd.name), shellQuote(strings.Join(args, " ")))
+		}
+	}
+}
+
+// appendOnce adds the string to the list, unless it is already there.
+func appendOnce(list []string, s string) []string {
+	for _, have := range list {
+		if have == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
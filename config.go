@@ -0,0 +1,192 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pg "github.com/wiremoons/passgen/lib"
+)
+
+// setting links a key in the configuration file, and its matching
+// environment variable, to the command line flag it sets a default for.
+type setting struct {
+	key     string // key used in the configuration file
+	flag    string // name of the command line flag
+	command string // only used by this command, if not empty
+}
+
+// settings holds every value that can be set in the configuration file or
+// the environment. Each applies to any command that has the flag, unless
+// it is limited to one command.
+var settings = []setting{
+	{"words", "w", ""},
+	{"suggestions", "s", ""},
+	{"mixed-case", "c", ""},
+	{"remove-spaces", "r", ""},
+	{"quiet", "q", "generate"},
+	{"unique", "unique", ""},
+	{"phonetic", "phonetic", ""},
+	{"clear", "clear", "generate"},
+	{"copy-clear", "copy-clear", ""},
+	{"non-tty", "non-tty", ""},
+	{"colour", "colour", ""},
+	{"policy", "policy", ""},
+	{"policy-file", "policy-file", ""},
+	{"pwquality", "pwquality", ""},
+	{"breach", "breach", ""},
+}
+
+// env returns the name of the environment variable for the setting, such
+// as 'PASSGEN_WORDS' for 'words'.
+func (s setting) env() string {
+	return "PASSGEN_" + strings.ToUpper(strings.Replace(s.key, "-", "_", -1))
+}
+
+// appliesTo reports if the setting is used by the command - 'config' shows
+// every setting.
+func (s setting) appliesTo(c *command) bool {
+	return s.command == "" || s.command == c.name || c.name == "config"
+}
+
+// usesSettings reports if the command, with the flag set given, has any
+// flag that can be set in the configuration file or environment.
+func usesSettings(c *command, fs *flag.FlagSet) bool {
+	for _, s := range settings {
+		if fs.Lookup(s.flag) != nil && s.appliesTo(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// settingSources records where the value of each flag came from, keyed on
+// the flag name, for 'config show'.
+var settingSources map[string]string
+
+// configPath returns the location of the configuration file. This is the
+// PASSGEN_CONFIG environment variable if set, otherwise 'passgen/config.toml'
+// in the user's configuration directory ($XDG_CONFIG_HOME or ~/.config on
+// Linux). An empty string is returned if there is no such directory.
+func configPath() string {
+	if path := os.Getenv("PASSGEN_CONFIG"); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "passgen", "config.toml")
+}
+
+// readConfig returns the settings in the configuration file at 'path', or
+// no settings if the file does not exist.
+func readConfig(path string) (pg.Table, error) {
+	if path == "" {
+		return pg.Table{}, nil
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return pg.Table{}, nil
+	} else if err != nil {
+		return nil, ioErrorf("unable to read the configuration file: %s", err)
+	}
+	defer f.Close()
+	tables, order, err := pg.ParseTOML(f)
+	if err != nil {
+		return nil, usageErrorf("configuration file %s: %s", path, err)
+	}
+	if len(order) > 0 {
+		return nil, usageErrorf("configuration file %s: tables such as [%s] are not used", path, order[0])
+	}
+	for key := range tables[""] {
+		if findSetting(key) == nil {
+			return nil, usageErrorf("configuration file %s: unknown setting '%s'", path, key)
+		}
+	}
+	return tables[""], nil
+}
+
+// findSetting returns the setting with the configuration file key given,
+// or nil if there is no such setting.
+func findSetting(key string) *setting {
+	for i := range settings {
+		if settings[i].key == key {
+			return &settings[i]
+		}
+	}
+	return nil
+}
+
+// applySettings sets the flags in the flag set that were not given on the
+// command line from the configuration file, and then from the environment,
+// so the priority is: command line flags, then environment, then
+// configuration file, then the built in defaults. It must be called after
+// the flags are parsed. Commands with none of the settings, such as 'help'
+// and 'version', do not read the configuration file - so a mistake in it
+// never blocks the help needed to fix it.
+func applySettings(c *command, fs *flag.FlagSet) error {
+	settingSources = map[string]string{}
+	fs.Visit(func(f *flag.Flag) { settingSources[f.Name] = "command line" })
+	if !usesSettings(c, fs) {
+		return nil
+	}
+	// the old '-h' and '-v' flags of 'generate' are the same as the 'help'
+	// and 'version' commands, so need no settings either
+	if settingSources["h"] != "" || settingSources["v"] != "" {
+		return nil
+	}
+	path := configPath()
+	config, err := readConfig(path)
+	if err != nil {
+		return err
+	}
+	for _, s := range settings {
+		if _, given := settingSources[s.flag]; given || fs.Lookup(s.flag) == nil {
+			continue
+		}
+		if !s.appliesTo(c) {
+			continue
+		}
+		if value, ok := config[s.key]; ok {
+			if err := fs.Set(s.flag, fmt.Sprint(value)); err != nil {
+				return usageErrorf("configuration file %s: invalid value '%v' for %s: %s", path, value, s.key, err)
+			}
+			settingSources[s.flag] = "config file " + path
+		}
+		if value, ok := os.LookupEnv(s.env()); ok {
+			if err := fs.Set(s.flag, value); err != nil {
+				return usageErrorf("invalid value '%s' for environment variable %s: %s", value, s.env(), err)
+			}
+			settingSources[s.flag] = "environment " + s.env()
+		}
+	}
+	return nil
+}
+
+// runConfig handles the 'config' sub command. 'config show' outputs the
+// value of every setting, and where it came from.
+func runConfig(fs *flag.FlagSet) int {
+	if fs.NArg() > 0 && fs.Arg(0) != "show" {
+		return fail(usageErrorf("unknown config action '%s' - use 'show'", fs.Arg(0)))
+	}
+	path := configPath()
+	status := "not found"
+	if _, err := os.Stat(path); err == nil {
+		status = "found"
+	}
+	fmt.Printf("Configuration file: %s (%s)\n", path, status)
+	fmt.Printf("Each setting can also be set with a PASSGEN_<SETTING> environment variable.\n\n")
+	fmt.Printf("%-14s %-13s %-24s %-24s %s\n", "SETTING", "FLAG", "ENVIRONMENT", "VALUE", "SOURCE")
+	for _, s := range settings {
+		f := fs.Lookup(s.flag)
+		source, ok := settingSources[s.flag]
+		if !ok {
+			source = "built in default"
+		}
+		fmt.Printf("%-14s %-13s %-24s %-24s %s\n", s.key, "-"+s.flag, s.env(), f.Value.String(), source)
+	}
+	return exitOK
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBadConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte("bogus = 1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	old, set := os.LookupEnv("PASSGEN_CONFIG")
+	os.Setenv("PASSGEN_CONFIG", path)
+	defer func() {
+		if set {
+			os.Setenv("PASSGEN_CONFIG", old)
+		} else {
+			os.Unsetenv("PASSGEN_CONFIG")
+		}
+	}()
+	// keep the help text and errors out of the test output
+	null, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer null.Close()
+	stdout, stderr := os.Stdout, errorOutput
+	os.Stdout, errorOutput = null, null
+	defer func() { os.Stdout, errorOutput = stdout, stderr }()
+
+	// commands without settings do not read the file, so still work
+	tests := []struct {
+		args []string
+		code int
+	}{
+		{[]string{"help"}, exitOK},
+		{[]string{"version"}, exitOK},
+		{[]string{"docs", "--man"}, exitOK},
+		{[]string{"completion", "bash"}, exitOK},
+		{[]string{"generate", "-h"}, exitOK},
+		{[]string{"generate", "-v"}, exitOK},
+		{[]string{"generate", "-q"}, exitUsage},
+		{[]string{"config"}, exitUsage},
+	}
+	for _, tt := range tests {
+		if code := findCommand(tt.args[0]).execute(tt.args[1:]); code != tt.code {
+			t.Errorf("%q exited with %d, want %d", tt.args, code, tt.code)
+		}
+	}
+}
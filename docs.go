@@ -0,0 +1,264 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	pg "github.com/wiremoons/passgen/lib"
+)
+
+// docsMan and docsMarkdown choose the format output by the 'docs' command
+var (
+	docsMan      bool
+	docsMarkdown bool
+)
+
+// docFiles holds the files read by the application, for the FILES section
+// of the reference documents.
+var docFiles = []struct{ path, desc string }{
+	{"$XDG_CONFIG_HOME/passgen/config.toml", "default settings for the options - see ENVIRONMENT for the settings available. Usually ~/.config/passgen/config.toml on Linux, and moved with PASSGEN_CONFIG"},
+	{"$XDG_CONFIG_HOME/passgen/policies.toml", "your own named password policies, added to the built in policy catalogue"},
+	{pg.PWQualityPath, "pam_pwquality rules, read with --pwquality"},
+}
+
+// docsFlags adds the flags used by the 'docs' sub command.
+func docsFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&docsMan, "man", false, "\tUSE: '--man' output a man page in roff format")
+	fs.BoolVar(&docsMarkdown, "markdown", false, "\tUSE: '--markdown' output a reference document in Markdown format")
+}
+
+// runDocs handles the 'docs' sub command, which outputs a man page or a
+// Markdown reference document. Both are made from the command table, the
+// flags of each command and the help text in the lib package, so they
+// always match the application.
+func runDocs(fs *flag.FlagSet) int {
+	switch {
+	case docsMan && docsMarkdown:
+		return fail(usageErrorf("choose just one of '--man' or '--markdown'"))
+	case docsMan:
+		manPage(os.Stdout)
+	case docsMarkdown:
+		markdownReference(os.Stdout)
+	default:
+		return fail(usageErrorf("choose the format to output with '--man' or '--markdown'"))
+	}
+	return exitOK
+}
+
+// flagDoc splits a flag usage string such as "\tUSE: '-w #' where # is the
+// number of ... [DEFAULT: 3]" into the examples of its use, "-w #", and the
+// description that follows them.
+func flagDoc(f *flag.Flag) (examples []string, desc string) {
+	desc = strings.TrimPrefix(strings.TrimSpace(f.Usage), "USE: ")
+	for strings.HasPrefix(desc, "'") {
+		i := strings.Index(desc[1:], "' ")
+		if i < 0 {
+			break
+		}
+		examples = append(examples, desc[1:i+1])
+		desc = strings.TrimPrefix(desc[i+3:], "or ")
+	}
+	if len(examples) == 0 {
+		examples = []string{"-" + f.Name}
+	}
+	// defaults found in the home directory of the user making the documents
+	// are shown relative to any home directory
+	if home, err := os.UserHomeDir(); err == nil && home != "" {
+		desc = strings.Replace(desc, home+string(os.PathSeparator), "~"+string(os.PathSeparator), -1)
+	}
+	return examples, desc
+}
+
+// docOption returns the flag as it is usually typed: '-w' for single letter
+// flags, or '--policy' for longer ones.
+func docOption(name string) string {
+	if len(name) == 1 {
+		return "-" + name
+	}
+	return "--" + name
+}
+
+// docParagraphs splits the text of a help section into its paragraphs.
+func docParagraphs(text string) [][]string {
+	var paras [][]string
+	for _, para := range strings.Split(text, "\n\n") {
+		paras = append(paras, strings.Split(para, "\n"))
+	}
+	return paras
+}
+
+// manEscape escapes text for use in a roff man page.
+func manEscape(s string) string {
+	s = strings.NewReplacer(`\`, `\e`, "-", `\-`).Replace(s)
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = `\&` + s
+	}
+	return s
+}
+
+// manPage writes the man page for the application, in roff format.
+func manPage(w io.Writer) {
+	name := strings.ToUpper(appname)
+	fmt.Fprintf(w, ".\\\" man page generated by '%s docs --man' - do not edit\n", appname)
+	fmt.Fprintf(w, ".TH %s 1 \"\" \"%s %s\" \"User Commands\"\n", name, appname, appversion)
+	fmt.Fprintf(w, ".SH NAME\n%s \\- generate passwords from a pool of three letter words\n", manEscape(appname))
+	fmt.Fprintf(w, ".SH SYNOPSIS\n.B %s\n[\\fIcommand\\fR] [\\fIoptions\\fR]\n", manEscape(appname))
+
+	// the first help section describes the application, and the rest follow
+	// the standard sections below
+	manSection(w, "DESCRIPTION", pg.HelpSections[0].Text)
+
+	fmt.Fprintf(w, ".SH COMMANDS\n")
+	fmt.Fprintf(w, "When no command is given, or the first argument is an option, the\n.B generate\ncommand is used.\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, ".TP\n.B %s %s\n%s\n", manEscape(cmd.name), manEscape(cmd.args), manEscape(cmd.summary))
+		fs := cmd.flagSet()
+		hasFlags := false
+		fs.VisitAll(func(f *flag.Flag) {
+			if !hasFlags {
+				fmt.Fprintf(w, ".RS\n")
+				hasFlags = true
+			}
+			examples, desc := flagDoc(f)
+			for i, ex := range examples {
+				examples[i] = "\\fB" + manEscape(ex) + "\\fR"
+			}
+			fmt.Fprintf(w, ".TP\n%s\n%s\n", strings.Join(examples, ", "), manEscape(desc))
+		})
+		if hasFlags {
+			fmt.Fprintf(w, ".RE\n")
+		}
+	}
+
+	fmt.Fprintf(w, ".SH ENVIRONMENT\n")
+	fmt.Fprintf(w, ".TP\n.B PASSGEN_CONFIG\nthe configuration file to read, in place of the default one below.\n")
+	for _, s := range settings {
+		fmt.Fprintf(w, ".TP\n.B %s\n", manEscape(s.env()))
+		fmt.Fprintf(w, "%s\n", manEscape(fmt.Sprintf("default for the %s option - also set with '%s' in the configuration file.", docOption(s.flag), s.key)))
+	}
+	fmt.Fprintf(w, ".PP\nOptions given on the command line take priority over the environment, which takes priority over the configuration file.\n")
+
+	fmt.Fprintf(w, ".SH FILES\n")
+	for _, f := range docFiles {
+		fmt.Fprintf(w, ".TP\n.I %s\n%s\n", manEscape(f.path), manEscape(f.desc))
+	}
+
+	fmt.Fprintf(w, ".SH EXIT STATUS\n")
+	for _, e := range exitCodes {
+		fmt.Fprintf(w, ".TP\n.B %d\n%s\n", e.code, manEscape(e.desc))
+	}
+
+	for _, section := range pg.HelpSections[1:] {
+		manSection(w, strings.ToUpper(section.Title), section.Text)
+	}
+}
+
+// manSection writes a section of help text to the man page. Paragraphs with
+// indented lines, such as the list of references, keep their line breaks.
+func manSection(w io.Writer, title, text string) {
+	fmt.Fprintf(w, ".SH %s\n", manEscape(title))
+	for i, para := range docParagraphs(text) {
+		if i > 0 {
+			fmt.Fprintf(w, ".PP\n")
+		}
+		nofill := false
+		for _, line := range para {
+			if strings.HasPrefix(line, " ") {
+				nofill = true
+			}
+		}
+		if nofill {
+			fmt.Fprintf(w, ".nf\n")
+		}
+		for _, line := range para {
+			fmt.Fprintf(w, "%s\n", manEscape(line))
+		}
+		if nofill {
+			fmt.Fprintf(w, ".fi\n")
+		}
+	}
+}
+
+// markdownEscape escapes text for use in a Markdown table cell.
+func markdownEscape(s string) string {
+	return strings.Replace(s, "|", `\|`, -1)
+}
+
+// markdownReference writes the reference document for the application, in
+// Markdown format.
+func markdownReference(w io.Writer) {
+	fmt.Fprintf(w, "<!-- generated by '%s docs --markdown' - do not edit -->\n\n", appname)
+	fmt.Fprintf(w, "# %s - %s\n\n", appname, pg.Title)
+	fmt.Fprintf(w, "Version %s\n\n", appversion)
+	fmt.Fprintf(w, "## Synopsis\n\n`%s [command] [options]`\n\n", appname)
+	markdownSection(w, "Description", pg.HelpSections[0].Text)
+
+	fmt.Fprintf(w, "## Commands\n\n")
+	fmt.Fprintf(w, "When no command is given, or the first argument is an option, the `generate` command is used.\n\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "### %s\n\n", cmd.name)
+		fmt.Fprintf(w, "`%s %s %s`\n\n", appname, cmd.name, cmd.args)
+		fmt.Fprintf(w, "%s.\n\n", strings.ToUpper(cmd.summary[:1])+cmd.summary[1:])
+		fs := cmd.flagSet()
+		hasFlags := false
+		fs.VisitAll(func(f *flag.Flag) {
+			if !hasFlags {
+				fmt.Fprintf(w, "| Option | Description |\n|--------|-------------|\n")
+				hasFlags = true
+			}
+			examples, desc := flagDoc(f)
+			for i, ex := range examples {
+				examples[i] = "`" + ex + "`"
+			}
+			fmt.Fprintf(w, "| %s | %s |\n", markdownEscape(strings.Join(examples, " or ")), markdownEscape(desc))
+		})
+		if hasFlags {
+			fmt.Fprintf(w, "\n")
+		}
+	}
+
+	fmt.Fprintf(w, "## Environment\n\n")
+	fmt.Fprintf(w, "Options given on the command line take priority over the environment, which takes priority over the configuration file.\n\n")
+	fmt.Fprintf(w, "| Variable | Setting | Option |\n|----------|---------|--------|\n")
+	fmt.Fprintf(w, "| `PASSGEN_CONFIG` | | the configuration file to read |\n")
+	for _, s := range settings {
+		fmt.Fprintf(w, "| `%s` | `%s` | `%s` |\n", s.env(), s.key, docOption(s.flag))
+	}
+
+	fmt.Fprintf(w, "\n## Files\n\n")
+	for _, f := range docFiles {
+		fmt.Fprintf(w, "- `%s` - %s\n", f.path, f.desc)
+	}
+
+	fmt.Fprintf(w, "\n## Exit Status\n\n| Code | Meaning |\n|------|---------|\n")
+	for _, e := range exitCodes {
+		fmt.Fprintf(w, "| %d | %s |\n", e.code, markdownEscape(e.desc))
+	}
+	fmt.Fprintf(w, "\n")
+
+	for _, section := range pg.HelpSections[1:] {
+		markdownSection(w, section.Title, section.Text)
+	}
+}
+
+// markdownSection writes a section of help text to the Markdown document.
+// Indented lines, such as the links in the list of references, are kept on
+// their own line.
+func markdownSection(w io.Writer, title, text string) {
+	fmt.Fprintf(w, "## %s\n\n", title)
+	for _, para := range docParagraphs(text) {
+		for i, line := range para {
+			if i+1 < len(para) && (strings.HasPrefix(line, " ") || strings.HasPrefix(para[i+1], " ")) {
+				line += "  "
+			}
+			if strings.HasPrefix(line, " - ") {
+				line = ` \- ` + line[3:]
+			}
+			fmt.Fprintf(w, "%s\n", line)
+		}
+		fmt.Fprintf(w, "\n")
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// exit codes used by the application, so scripts can tell a failed run
+// apart from real output. These are listed in the help text.
+const (
+	exitOK     = 0 // success
+	exitFail   = 1 // a password failed a check, such as with 'verify'
+	exitUsage  = 2 // bad command line arguments
+	exitPolicy = 3 // the password policy, or '--unique', can not be met
+	exitIO     = 4 // unable to read or write a file, or stdin
+)
+
+// exitCodes describes each exit code for the help text and documentation.
+var exitCodes = []struct {
+	code int
+	desc string
+}{
+	{exitOK, "success"},
+	{exitFail, "the password checked failed - such as breaking a policy rule with 'verify'"},
+	{exitUsage, "usage error - bad command, option or option value"},
+	{exitPolicy, "the password policy can not be met, or no new password is left for '--unique'"},
+	{exitIO, "I/O error - unable to read or write a file, or stdin"},
+}
+
+// errorOutput is where fail reports errors. It is stderr, unless the errors
+// are being held back to show later - such as while '--clear' is using the
+// alternate screen.
+var errorOutput io.Writer = os.Stderr
+
+// exitError is an error that carries the exit code to use when the
+// application stops because of it.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+
+// usageErrorf returns an error for a bad command line argument.
+func usageErrorf(format string, a ...interface{}) error {
+	return &exitError{exitUsage, fmt.Errorf(format, a...)}
+}
+
+// policyErrorf returns an error for a password policy that can not be met.
+func policyErrorf(format string, a ...interface{}) error {
+	return &exitError{exitPolicy, fmt.Errorf(format, a...)}
+}
+
+// ioErrorf returns an error for a failure to read or write a file.
+func ioErrorf(format string, a ...interface{}) error {
+	return &exitError{exitIO, fmt.Errorf(format, a...)}
+}
+
+// fail reports the error on stderr, and returns the exit code to use. An
+// error without an exit code of its own uses exitFail.
+func fail(err error) int {
+	fmt.Fprintf(errorOutput, "ERROR: %s\n", err)
+	var e *exitError
+	if errors.As(err, &e) {
+		return e.code
+	}
+	return exitFail
+}
+
+// checkRange returns a usage error if the value given for a flag is not
+// between min and max.
+func checkRange(flagName string, value, min, max int) error {
+	if value < min || value > max {
+		return usageErrorf("-%s must be between %d and %d, not %d", flagName, min, max, value)
+	}
+	return nil
+}
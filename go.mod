@@ -1,3 +1,8 @@
 module github.com/wiremoons/passgen
 
 go 1.16
+
+require (
+	golang.org/x/crypto v0.23.0
+	golang.org/x/term v0.20.0
+)
@@ -0,0 +1,179 @@
+package main
+
+import (
+	crand "crypto/rand"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"strings"
+
+	pg "github.com/wiremoons/passgen/lib"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// hashList is set by '--hash' to the names of the hash formats to output
+// with each password, and noPlaintext by '--no-plaintext' to output just
+// the hashes, leaving out the passwords themselves
+var hashList string
+var noPlaintext bool
+
+// outputHashes holds the hash formats chosen with '--hash' for the
+// passwords output by 'generate', once checked by checkHash.
+var outputHashes []hashFormat
+
+// hashFormat is a way of hashing a password, chosen by name with '--hash'.
+type hashFormat struct {
+	name string
+	desc string
+	hash func(password string) (string, error)
+}
+
+// hashFormats holds the formats that can be chosen with '--hash'. Add new
+// formats here - they are listed in the help text and completion from this
+// list.
+var hashFormats = []hashFormat{
+	{"sha512-crypt", "SHA-512 crypt, the '$6$' format of /etc/shadow and 'chpasswd -e'", hashSHA512Crypt},
+	{"bcrypt", fmt.Sprintf("bcrypt with cost %d, in the '$2a$' format", bcryptCost), hashBcrypt},
+	{"argon2id", fmt.Sprintf("Argon2id in the PHC string format, using %d MiB of memory, %d passes and %d lanes", argon2Memory/1024, argon2Time, argon2Threads), hashArgon2id},
+	{"htpasswd", "bcrypt in the '$2y$' format written by 'htpasswd -B', for Apache and nginx", hashHtpasswd},
+}
+
+// the cost of bcrypt hashes, and the settings for Argon2id hashes - those
+// recommended in RFC 9106 for use with less memory
+const (
+	bcryptCost     = bcrypt.DefaultCost
+	argon2Time     = 3
+	argon2Memory   = 64 * 1024 // KiB
+	argon2Threads  = 4
+	argon2KeyLen   = 32
+	argon2SaltLen  = 16
+	cryptSaltChars = 16
+)
+
+// hashFlags adds the flags used to choose the hashes output with each
+// password.
+func hashFlags(fs *flag.FlagSet) {
+	fs.StringVar(&hashList, "hash", "", "\tUSE: '--hash NAME,NAME' also output each password hashed in each format named: "+strings.Join(hashNames(), ", "))
+	fs.BoolVar(&noPlaintext, "no-plaintext", false, "\tUSE: '--no-plaintext' with '--hash' leave out the passwords, and output just the hashes [DEFAULT: passwords included]")
+}
+
+// checkHash returns an error if '--hash' or '--no-plaintext' can not be
+// used with the other options given to 'generate', and sets outputHashes to
+// the hash formats chosen. The hashes follow each password on its line, so
+// they need the one per line output of '-q' or '--out'.
+func checkHash() error {
+	var err error
+	if outputHashes, err = selectHashes(hashList); err != nil {
+		return err
+	}
+	if len(outputHashes) == 0 {
+		if noPlaintext {
+			return usageErrorf("'--no-plaintext' would leave no passwords - use it with '--hash'")
+		}
+		return nil
+	}
+	if !quiet && outPath == "" {
+		return usageErrorf("'--hash' outputs each password and its hashes on one line - use it with '-q' or '--out'")
+	}
+	if interactive || copyPassword || qrShow || qrFile != "" {
+		return usageErrorf("'--hash' can not be used with '-i', '--copy', '--qr' or '--qr-file'")
+	}
+	if outNames != "" {
+		return usageErrorf("'--hash' can not be used with '--names', as each file holds just the password")
+	}
+	if noPlaintext && phonetic {
+		return usageErrorf("'--phonetic' would spell out the passwords left out by '--no-plaintext'")
+	}
+	return nil
+}
+
+// withHashes returns the line output for a password with '--hash': the
+// password as shown, then its hash in each format chosen, separated by
+// tabs - or just the hashes with '--no-plaintext'. Without '--hash' the
+// password is returned as shown.
+func withHashes(shown, password string) (string, error) {
+	if len(outputHashes) == 0 {
+		return shown, nil
+	}
+	var fields []string
+	if !noPlaintext {
+		fields = append(fields, shown)
+	}
+	for _, h := range outputHashes {
+		hash, err := h.hash(password)
+		if err != nil {
+			return "", fmt.Errorf("unable to hash the password with %s: %s", h.name, err)
+		}
+		fields = append(fields, hash)
+	}
+	return strings.Join(fields, "\t"), nil
+}
+
+// hashNames returns the names of the hash formats available.
+func hashNames() []string {
+	var names []string
+	for _, h := range hashFormats {
+		names = append(names, h.name)
+	}
+	return names
+}
+
+// selectHashes returns the hash formats named in the comma separated list,
+// in the order given.
+func selectHashes(list string) ([]hashFormat, error) {
+	var chosen []hashFormat
+	for _, name := range strings.Split(list, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, h := range hashFormats {
+			if h.name == name {
+				chosen = append(chosen, h)
+				found = true
+			}
+		}
+		if !found {
+			return nil, usageErrorf("unknown hash format '%s' - use one of: %s", name, strings.Join(hashNames(), ", "))
+		}
+	}
+	return chosen, nil
+}
+
+// hashSHA512Crypt returns the password hashed with SHA-512 crypt, with a
+// new random salt.
+func hashSHA512Crypt(password string) (string, error) {
+	salt := make([]byte, cryptSaltChars)
+	for i := range salt {
+		salt[i] = pg.CryptAlphabet[rng.Intn(len(pg.CryptAlphabet))]
+	}
+	return pg.SHA512Crypt(password, string(salt), pg.SHA512CryptRounds), nil
+}
+
+// hashBcrypt returns the password hashed with bcrypt.
+func hashBcrypt(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost)
+	return string(hash), err
+}
+
+// hashHtpasswd returns the password hashed with bcrypt, marked '$2y$' as
+// 'htpasswd -B' does. The '$2a$' and '$2y$' forms only differ in how other
+// implementations once handled non-ASCII passwords, so the hash is the same.
+func hashHtpasswd(password string) (string, error) {
+	hash, err := hashBcrypt(password)
+	return strings.Replace(hash, "$2a$", "$2y$", 1), err
+}
+
+// hashArgon2id returns the password hashed with Argon2id, with a new random
+// salt, in the PHC string format: $argon2id$v=19$m=...,t=...,p=...$salt$hash
+func hashArgon2id(password string) (string, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := crand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, argon2Memory, argon2Time, argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
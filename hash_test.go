@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pg "github.com/wiremoons/passgen/lib"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// the password hashed by each test, with a space and non-ASCII letter
+const hashPassword = "cat dog pïg"
+
+func TestHashSHA512Crypt(t *testing.T) {
+	hash, err := hashSHA512Crypt(hashPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// $6$salt$hash, with the default rounds left out
+	fields := strings.Split(hash, "$")
+	if len(fields) != 4 || fields[1] != "6" || len(fields[2]) != cryptSaltChars || len(fields[3]) != 86 {
+		t.Fatalf("hashSHA512Crypt gave %s, want $6$ with a %d character salt", hash, cryptSaltChars)
+	}
+	if again := pg.SHA512Crypt(hashPassword, fields[2], pg.SHA512CryptRounds); again != hash {
+		t.Errorf("hashSHA512Crypt gave %s, but the password hashes to %s with its salt", hash, again)
+	}
+	if other, _ := hashSHA512Crypt(hashPassword); other == hash {
+		t.Errorf("hashSHA512Crypt gave the same salt twice: %s", hash)
+	}
+}
+
+func TestHashBcrypt(t *testing.T) {
+	tests := []struct {
+		hash   func(string) (string, error)
+		prefix string
+	}{
+		{hashBcrypt, fmt.Sprintf("$2a$%02d$", bcryptCost)},
+		{hashHtpasswd, fmt.Sprintf("$2y$%02d$", bcryptCost)},
+	}
+	for _, tt := range tests {
+		hash, err := tt.hash(hashPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(hash, tt.prefix) || len(hash) != 60 {
+			t.Errorf("%s is not a bcrypt hash starting %s", hash, tt.prefix)
+		}
+		// bcrypt compares '$2y$' hashes as it does '$2a$' hashes
+		if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(hashPassword)); err != nil {
+			t.Errorf("%s does not match the password: %v", hash, err)
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte("cat dog pig")); err == nil {
+			t.Errorf("%s matches another password", hash)
+		}
+	}
+}
+
+func TestHashArgon2id(t *testing.T) {
+	hash, err := hashArgon2id(hashPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// $argon2id$v=19$m=65536,t=3,p=4$salt$key
+	fields := strings.Split(hash, "$")
+	if len(fields) != 6 || fields[1] != "argon2id" {
+		t.Fatalf("hashArgon2id gave %s, not the PHC string format", hash)
+	}
+	if want := fmt.Sprintf("v=%d", argon2.Version); fields[2] != want {
+		t.Errorf("version %s, want %s", fields[2], want)
+	}
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(fields[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		t.Fatalf("parameters %s: %v", fields[3], err)
+	}
+	if memory != argon2Memory || time != argon2Time || threads != argon2Threads {
+		t.Errorf("parameters %s, want m=%d,t=%d,p=%d", fields[3], argon2Memory, argon2Time, argon2Threads)
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(fields[4])
+	if err != nil || len(salt) != argon2SaltLen {
+		t.Fatalf("salt %s: %d bytes, %v", fields[4], len(salt), err)
+	}
+	// the key is found again from the password and the salt and settings
+	key := argon2.IDKey([]byte(hashPassword), salt, time, memory, threads, argon2KeyLen)
+	if fields[5] != base64.RawStdEncoding.EncodeToString(key) {
+		t.Errorf("hashArgon2id gave %s, which the password does not hash to", hash)
+	}
+}
+
+func TestSelectHashes(t *testing.T) {
+	tests := []struct {
+		list string
+		want string // the names chosen, or the error
+	}{
+		{"", ""},
+		{"argon2id", "argon2id"},
+		{" BCRYPT , argon2id ", "bcrypt,argon2id"},
+		{"htpasswd,,sha512-crypt", "htpasswd,sha512-crypt"},
+		{"sha256", "unknown hash format 'sha256' - use one of: " + strings.Join(hashNames(), ", ")},
+		{"md5", "unknown hash format 'md5' - use one of: " + strings.Join(hashNames(), ", ")},
+	}
+	for _, tt := range tests {
+		hashes, err := selectHashes(tt.list)
+		var names []string
+		for _, h := range hashes {
+			names = append(names, h.name)
+		}
+		got := strings.Join(names, ",")
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tt.want {
+			t.Errorf("selectHashes(%q) = %s, want %s", tt.list, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateHash(t *testing.T) {
+	tests := []struct {
+		args   []string
+		fields int    // the fields on each line
+		prefix string // the start of the last field
+	}{
+		{[]string{"--hash", "sha512-crypt"}, 2, "$6$"},
+		{[]string{"--hash", "sha512-crypt,htpasswd"}, 3, "$2y$"},
+		{[]string{"--hash", "bcrypt", "--no-plaintext"}, 1, "$2a$"},
+		{[]string{"--policy", "pci-dss", "--hash", "sha512-crypt"}, 2, "$6$"},
+	}
+	for _, tt := range tests {
+		out := filepath.Join(t.TempDir(), "passwords.txt")
+		args := append([]string{"-s", "2", "--out", out}, tt.args...)
+		if code := runCommand(t, "generate", args...); code != exitOK {
+			t.Errorf("%q: generate exited with %d", tt.args, code)
+			continue
+		}
+		data, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+		if len(lines) != 2 {
+			t.Errorf("%q: %d lines, want 2", tt.args, len(lines))
+		}
+		for _, line := range lines {
+			fields := strings.Split(line, "\t")
+			if len(fields) != tt.fields || !strings.HasPrefix(fields[len(fields)-1], tt.prefix) {
+				t.Errorf("%q: line %q, want %d fields ending %s...", tt.args, line, tt.fields, tt.prefix)
+				continue
+			}
+			// each hash is of the password at the start of the line
+			if tt.fields > 1 && strings.HasPrefix(fields[1], "$6$") {
+				salt := strings.Split(fields[1], "$")[2]
+				if pg.SHA512Crypt(fields[0], salt, pg.SHA512CryptRounds) != fields[1] {
+					t.Errorf("%q: %s is not the hash of %q", tt.args, fields[1], fields[0])
+				}
+			}
+		}
+	}
+}
+
+func TestGenerateHashErrors(t *testing.T) {
+	tests := [][]string{
+		{"--hash", "sha512-crypt"},
+		{"-i", "--hash", "sha512-crypt"},
+		{"-q", "--copy", "--hash", "sha512-crypt"},
+		{"-q", "--qr", "--hash", "sha512-crypt"},
+		{"-q", "--hash", "sha256"},
+		{"-q", "--no-plaintext"},
+		{"-q", "--hash", "bcrypt", "--no-plaintext", "--phonetic"},
+		{"--out", os.DevNull, "--names", "a,b", "--hash", "bcrypt"},
+	}
+	for _, args := range tests {
+		if code := runCommand(t, "generate", args...); code != exitUsage {
+			t.Errorf("%q: generate exited with %d, want %d", args, code, exitUsage)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+
+	pg "github.com/wiremoons/passgen/lib"
+)
+
+// interactive is set by '-i' to choose a password in an interactive UI
+var interactive bool
+
+// caseStrategies and separators are cycled through with the 'c' and 's'
+// keys in the interactive UI
+var caseStrategies = []string{"lower", "mixed", "title"}
+var separators = []string{" ", "", "-", "."}
+
+// cell is one word of a password suggestion in the interactive UI.
+type cell struct {
+	word   string // the word in lower case
+	mixed  string // the word in mixed case, kept so it is the same each time mixed case is chosen
+	locked bool   // locked words are kept when the suggestion is re-rolled
+}
+
+// chooser holds the state of the interactive UI: the suggestions, the word
+// selected, and the case and separator in use.
+type chooser struct {
+	rows     [][]cell
+	row, col int
+	caseIdx  int
+	sepIdx   int
+	message  string
+}
+
+// newChooser returns a chooser holding new password suggestions. The case
+// and separator start as chosen with '-c' and '-r'.
+func newChooser(suggestions, words int) *chooser {
+	c := &chooser{rows: make([][]cell, suggestions)}
+	for r := range c.rows {
+		c.rows[r] = make([]cell, words)
+		for w := range c.rows[r] {
+			c.roll(r, w)
+		}
+	}
+	if passcase {
+		c.caseIdx = 1
+	}
+	if remove {
+		c.sepIdx = 1
+	}
+	return c
+}
+
+// roll chooses a new word for the cell, unless the word is locked.
+func (c *chooser) roll(r, w int) {
+	if c.rows[r][w].locked {
+		return
+	}
+	word := getPassword(1)
+	c.rows[r][w] = cell{word: word, mixed: mixedPassword(word)}
+}
+
+// form returns the word in the case strategy in use.
+func (c *chooser) form(w cell) string {
+	switch caseStrategies[c.caseIdx] {
+	case "mixed":
+		return w.mixed
+	case "title":
+		runes := []rune(w.word)
+		runes[0] = unicode.ToUpper(runes[0])
+		return string(runes)
+	}
+	return w.word
+}
+
+// password returns the suggestion in the row as it would be used.
+func (c *chooser) password(r int) string {
+	words := make([]string, len(c.rows[r]))
+	for w, cl := range c.rows[r] {
+		words[w] = c.form(cl)
+	}
+	return strings.Join(words, separators[c.sepIdx])
+}
+
+// entropy returns the bits of entropy of a suggestion with the case
+// strategy in use. Capitalising the first letter of each word adds nothing,
+// as an attacker would try that first.
+func (c *chooser) entropy() float64 {
+	return pg.Plan{Words: len(c.rows[0]), Mixed: caseStrategies[c.caseIdx] == "mixed"}.Entropy()
+}
+
+// handle acts on a key press. It returns true when the UI is finished, and
+// whether a password was chosen. An error ends the UI if the breach corpus
+// can not be searched.
+func (c *chooser) handle(key string) (done, chosen bool, err error) {
+	c.message = ""
+	switch key {
+	case "up", "k":
+		c.row = (c.row + len(c.rows) - 1) % len(c.rows)
+	case "down", "j":
+		c.row = (c.row + 1) % len(c.rows)
+	case "left", "h":
+		c.col = (c.col + len(c.rows[c.row]) - 1) % len(c.rows[c.row])
+	case "right", "l":
+		c.col = (c.col + 1) % len(c.rows[c.row])
+	case "r":
+		if c.rows[c.row][c.col].locked {
+			c.message = "that word is locked - press space to unlock it"
+		}
+		c.roll(c.row, c.col)
+	case "R":
+		for w := range c.rows[c.row] {
+			c.roll(c.row, w)
+		}
+	case " ":
+		c.rows[c.row][c.col].locked = !c.rows[c.row][c.col].locked
+	case "c":
+		c.caseIdx = (c.caseIdx + 1) % len(caseStrategies)
+	case "s":
+		c.sepIdx = (c.sepIdx + 1) % len(separators)
+	case "enter":
+		found, err := breached(c.password(c.row))
+		if err != nil {
+			return true, false, err
+		}
+		if found {
+			c.message = "that password is in the breach corpus - re-roll a word"
+			return false, false, nil
+		}
+		return true, true, nil
+	case "q", "esc", "ctrl-c":
+		return true, false, nil
+	}
+	return false, false, nil
+}
+
+// draw outputs the UI to w, which is a terminal in raw mode - so each line
+// ends with a carriage return as well as a new line.
+func (c *chooser) draw(w io.Writer) {
+	var b strings.Builder
+	b.WriteString(clearScreen)
+	fmt.Fprintf(&b, "\t\t%s - INTERACTIVE\r\n\t\t%s\r\n", pg.Title, strings.Repeat("¯", len(pg.Title)+14))
+	sep := separators[c.sepIdx]
+	if sep == "" {
+		sep = "none"
+	} else {
+		sep = "'" + sep + "'"
+	}
+	fmt.Fprintf(&b, "» Case: %s   Separator: %s   Entropy: %.1f bits per password\r\n\r\n", caseStrategies[c.caseIdx], sep, c.entropy())
+	for r, row := range c.rows {
+		pointer := "  "
+		if r == c.row {
+			pointer = "» "
+		}
+		b.WriteString(pointer)
+		for w, cl := range row {
+			word := c.form(cl)
+			if cl.locked {
+				word = underlined + word + resetStyle
+			}
+			if r == c.row && w == c.col {
+				word = reverseVideo + word + resetStyle
+			}
+			fmt.Fprintf(&b, " %s", word)
+		}
+		fmt.Fprintf(&b, "    %s\r\n", paint(c.password(r)))
+	}
+	if phonetic {
+		fmt.Fprintf(&b, "\r\n» Spelled out: %s\r\n", pg.Phonetic(c.password(c.row)))
+	}
+	fmt.Fprintf(&b, "\r\n%s\r\n\r\n", c.message)
+	b.WriteString("arrows or h/j/k/l: select word    r: re-roll word    R: re-roll password    space: lock word\r\n")
+	b.WriteString("c: change case    s: change separator    enter: choose password    q: quit\r\n")
+	io.WriteString(w, b.String())
+}
+
+// runInteractive shows the password suggestions in an interactive UI on
+// the alternate screen, so words can be re-rolled and locked, and the case
+// and separators changed, before a password is chosen. The chosen password
+// is output once the normal screen is restored, or copied to the clipboard
+// with '--copy'. The exit code to use is returned.
+func runInteractive() int {
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+		return fail(usageErrorf("'-i' needs a terminal for both input and output"))
+	}
+	c := newChooser(numsuggestions, numwords)
+	restore, err := rawTerminal()
+	if err != nil {
+		return fail(err)
+	}
+	fmt.Print(altScreenOn + hideCursor)
+
+	keys := &keyReader{r: os.Stdin}
+	chosen := false
+	var handleErr error
+	for done := false; !done; {
+		c.draw(os.Stdout)
+		key, err := keys.readKey()
+		if err != nil {
+			break
+		}
+		done, chosen, handleErr = c.handle(key)
+	}
+
+	// the terminal is restored before any error is shown
+	fmt.Print(showCursor + altScreenOff)
+	restore()
+	if handleErr != nil {
+		return fail(handleErr)
+	}
+	if !chosen {
+		return exitOK
+	}
+	if copyPassword {
+		if err := outputQR(c.password(c.row)); err != nil {
+			return fail(err)
+		}
+		return copyToClipboard(c.password(c.row))
+	}
+	fmt.Println(paint(c.password(c.row)))
+	if phonetic {
+		fmt.Println(pg.Phonetic(c.password(c.row)))
+	}
+	if err := outputQR(c.password(c.row)); err != nil {
+		return fail(err)
+	}
+	return exitOK
+}
@@ -0,0 +1,238 @@
+package lib
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/crypto/md4"
+)
+
+// BreachCorpus looks up passwords in a locally downloaded copy of the
+// 'Pwned Passwords' list from https://haveibeenpwned.com/Passwords. Either
+// a single file of 'HASH:count' lines sorted by hash is used, which is
+// searched with a binary search so no index is needed, or a directory of
+// hash range files, each named after the first five characters of the
+// hashes it holds and containing 'SUFFIX:count' lines. SHA-1 and NTLM
+// hashes are both supported, and the type in use is found from the length
+// of the hashes in the file.
+type BreachCorpus struct {
+	path string
+	dir  bool
+	ntlm bool
+	file *os.File
+	size int64
+}
+
+// OpenBreachCorpus opens the breach corpus file or directory at 'path'.
+func OpenBreachCorpus(path string) (*BreachCorpus, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	b := &BreachCorpus{path: path, dir: info.IsDir()}
+
+	// find the first line in the corpus, to get the length of a hash
+	var sample string
+	if b.dir {
+		names, err := filepath.Glob(filepath.Join(path, "[0-9A-Fa-f][0-9A-Fa-f][0-9A-Fa-f][0-9A-Fa-f][0-9A-Fa-f]*"))
+		if err != nil || len(names) == 0 {
+			return nil, fmt.Errorf("%s: no hash range files found", path)
+		}
+		if sample, err = firstLine(names[0]); err != nil {
+			return nil, err
+		}
+		// range file lines hold the hash without its five character prefix
+		sample = filepath.Base(names[0])[:5] + sample
+	} else {
+		if b.file, err = os.Open(path); err != nil {
+			return nil, err
+		}
+		b.size = info.Size()
+		if sample, err = firstLine(path); err != nil {
+			b.file.Close()
+			return nil, err
+		}
+	}
+	hash := strings.SplitN(sample, ":", 2)[0]
+	switch len(hash) {
+	case 40:
+		b.ntlm = false
+	case 32:
+		b.ntlm = true
+	default:
+		b.Close()
+		return nil, fmt.Errorf("%s: expected SHA-1 or NTLM 'HASH:count' lines, found %q", path, sample)
+	}
+	return b, nil
+}
+
+// Close releases the open corpus file.
+func (b *BreachCorpus) Close() error {
+	if b.file != nil {
+		return b.file.Close()
+	}
+	return nil
+}
+
+// Kind returns the type of hash used by the corpus: "SHA-1" or "NTLM".
+func (b *BreachCorpus) Kind() string {
+	if b.ntlm {
+		return "NTLM"
+	}
+	return "SHA-1"
+}
+
+// Count returns the number of times the password was seen in a breach, or
+// zero if it is not in the corpus.
+func (b *BreachCorpus) Count(password string) (int, error) {
+	hash := b.hash(password)
+	if b.dir {
+		return b.countRange(hash)
+	}
+	return b.search(hash)
+}
+
+// hash returns the upper case hex hash of the password, as used in the
+// corpus. NTLM hashes are the MD4 hash of the UTF-16 little endian password.
+func (b *BreachCorpus) hash(password string) string {
+	if !b.ntlm {
+		sum := sha1.Sum([]byte(password))
+		return strings.ToUpper(hex.EncodeToString(sum[:]))
+	}
+	h := md4.New()
+	for _, u := range utf16.Encode([]rune(password)) {
+		binary.Write(h, binary.LittleEndian, u)
+	}
+	return strings.ToUpper(hex.EncodeToString(h.Sum(nil)))
+}
+
+// countRange looks up the hash in the range file named after its first
+// five characters. The range files are small, so are read in full.
+func (b *BreachCorpus) countRange(hash string) (int, error) {
+	var f *os.File
+	var err error
+	for _, name := range []string{hash[:5], hash[:5] + ".txt", strings.ToLower(hash[:5]), strings.ToLower(hash[:5]) + ".txt"} {
+		if f, err = os.Open(filepath.Join(b.path, name)); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if suffix, count, ok := splitLine(scanner.Text()); ok && strings.EqualFold(suffix, hash[5:]) {
+			return count, nil
+		}
+	}
+	return 0, scanner.Err()
+}
+
+// search finds the hash in the sorted corpus file with a binary search on
+// the file offsets, reading the first whole line after each offset tried.
+func (b *BreachCorpus) search(hash string) (int, error) {
+	lo, hi := int64(0), b.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		start, next, line, err := b.lineFrom(mid)
+		if err != nil {
+			return 0, err
+		}
+		if start >= hi {
+			// no line starts between mid and hi - so search the lower half
+			hi = mid
+			continue
+		}
+		found, count, ok := splitLine(line)
+		if !ok {
+			return 0, fmt.Errorf("%s: badly formed line at offset %d: %q", b.path, start, line)
+		}
+		switch strings.Compare(strings.ToUpper(found), hash) {
+		case 0:
+			return count, nil
+		case -1:
+			lo = next
+		default:
+			hi = mid
+		}
+	}
+	return 0, nil
+}
+
+// lineFrom returns the first line starting at or after the offset, along
+// with the offsets of its start and of the line after it.
+func (b *BreachCorpus) lineFrom(offset int64) (start, next int64, line string, err error) {
+	buf := make([]byte, 256)
+	readAt := offset
+	if offset > 0 {
+		// include the byte before, to see if a line starts at the offset
+		readAt = offset - 1
+	}
+	n, err := b.file.ReadAt(buf, readAt)
+	if err != nil && err != io.EOF {
+		return 0, 0, "", err
+	}
+	buf = buf[:n]
+	begin := 0
+	if offset > 0 {
+		i := bytes.IndexByte(buf, '\n')
+		if i < 0 {
+			return b.size, b.size, "", nil
+		}
+		begin = i + 1
+	}
+	start = readAt + int64(begin)
+	rest := buf[begin:]
+	end := bytes.IndexByte(rest, '\n')
+	if end < 0 {
+		end = len(rest)
+		next = start + int64(end)
+	} else {
+		next = start + int64(end) + 1
+	}
+	return start, next, strings.TrimRight(string(rest[:end]), "\r"), nil
+}
+
+// splitLine splits a 'HASH:count' line into its parts.
+func splitLine(line string) (string, int, bool) {
+	parts := strings.SplitN(strings.TrimSpace(line), ":", 2)
+	if len(parts) != 2 {
+		return "", 0, false
+	}
+	count, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return "", 0, false
+	}
+	return parts[0], count, true
+}
+
+// firstLine returns the first line of the file at 'path'.
+func firstLine(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("%s: file is empty", path)
+	}
+	return strings.TrimSpace(scanner.Text()), nil
+}
@@ -0,0 +1,164 @@
+package lib
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// the SHA-1 and NTLM hashes of "password", as listed by Pwned Passwords
+const (
+	passwordSHA1 = "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8"
+	passwordNTLM = "8846F7EAEE8FB117AD06BDD830B7586C"
+)
+
+// writeCorpus writes a sorted 'HASH:count' corpus file, with the line
+// ending given, for the lines given plus the SHA-1 of 'pw0' to 'pw499'.
+func writeCorpus(t *testing.T, lines []string, ending string) string {
+	for i := 0; i < 500; i++ {
+		sum := sha1.Sum([]byte(fmt.Sprintf("pw%d", i)))
+		lines = append(lines, fmt.Sprintf("%s:%d", strings.ToUpper(hex.EncodeToString(sum[:])), i+1))
+	}
+	sort.Strings(lines)
+	path := filepath.Join(t.TempDir(), "pwned-passwords-sha1-ordered-by-hash.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, ending)+ending), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestBreachCorpusFile(t *testing.T) {
+	for _, ending := range []string{"\r\n", "\n"} {
+		path := writeCorpus(t, []string{passwordSHA1 + ":9545824"}, ending)
+		b, err := OpenBreachCorpus(path)
+		if err != nil {
+			t.Fatalf("OpenBreachCorpus returned %v", err)
+		}
+		if b.Kind() != "SHA-1" {
+			t.Errorf("Kind() = %s, want SHA-1", b.Kind())
+		}
+		tests := []struct {
+			password string
+			count    int
+		}{
+			{"password", 9545824},
+			{"pw0", 1},
+			{"pw250", 251},
+			{"pw499", 500},
+			{"cat dog pig", 0},
+			{"", 0},
+		}
+		for _, tt := range tests {
+			count, err := b.Count(tt.password)
+			if err != nil || count != tt.count {
+				t.Errorf("%q endings: Count(%q) = %d, %v, want %d", ending, tt.password, count, err, tt.count)
+			}
+		}
+		// every entry is found by the binary search
+		for i := 0; i < 500; i++ {
+			if count, err := b.Count(fmt.Sprintf("pw%d", i)); err != nil || count != i+1 {
+				t.Errorf("%q endings: Count(pw%d) = %d, %v, want %d", ending, i, count, err, i+1)
+			}
+		}
+		b.Close()
+	}
+}
+
+func TestBreachCorpusNTLM(t *testing.T) {
+	lines := "0000000CAEF405439D57847A8657218C:1\r\n" + passwordNTLM + ":8846\r\nFFFFFFFF000000000000000000000000:2\r\n"
+	path := filepath.Join(t.TempDir(), "pwned-passwords-ntlm-ordered-by-hash.txt")
+	if err := os.WriteFile(path, []byte(lines), 0600); err != nil {
+		t.Fatal(err)
+	}
+	b, err := OpenBreachCorpus(path)
+	if err != nil {
+		t.Fatalf("OpenBreachCorpus returned %v", err)
+	}
+	defer b.Close()
+	if b.Kind() != "NTLM" {
+		t.Errorf("Kind() = %s, want NTLM", b.Kind())
+	}
+	if count, err := b.Count("password"); err != nil || count != 8846 {
+		t.Errorf("Count(password) = %d, %v, want 8846", count, err)
+	}
+	if count, err := b.Count("Password"); err != nil || count != 0 {
+		t.Errorf("Count(Password) = %d, %v, want 0", count, err)
+	}
+}
+
+func TestBreachCorpusRange(t *testing.T) {
+	dir := t.TempDir()
+	// range files as downloaded from the API, with CRLF line endings
+	files := map[string]string{
+		"5BAA6":     "003D68EB55068C33ACE09247EE4C639306B:3\r\n1E4C9B93F3F0682250B6CF8331B7EE68FD8:9545824\r\n1F2B668E8AABEF1C59E9EC6F82E3F3CD786:1\r\n",
+		"00000.txt": "0005AD76BD555C1D6D771DE417A4B87E4B4:10\r\n",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, err := OpenBreachCorpus(dir)
+	if err != nil {
+		t.Fatalf("OpenBreachCorpus returned %v", err)
+	}
+	defer b.Close()
+	tests := []struct {
+		password string
+		count    int
+	}{
+		{"password", 9545824},
+		{"cat dog pig", 0},
+	}
+	for _, tt := range tests {
+		if count, err := b.Count(tt.password); err != nil || count != tt.count {
+			t.Errorf("Count(%q) = %d, %v, want %d", tt.password, count, err, tt.count)
+		}
+	}
+}
+
+func TestOpenBreachCorpusErrors(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	tests := []struct {
+		path string
+		want string
+	}{
+		{filepath.Join(dir, "missing.txt"), "no such file"},
+		{write("empty.txt", ""), "file is empty"},
+		{write("words.txt", "password\r\n"), "expected SHA-1 or NTLM 'HASH:count' lines"},
+		{t.TempDir(), "no hash range files found"},
+	}
+	for _, tt := range tests {
+		b, err := OpenBreachCorpus(tt.path)
+		if err == nil {
+			b.Close()
+			t.Errorf("OpenBreachCorpus(%s) returned no error", tt.path)
+		} else if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("OpenBreachCorpus(%s) returned %v, want %q", tt.path, err, tt.want)
+		}
+	}
+}
+
+func TestBreachCorpusBadLine(t *testing.T) {
+	path := writeCorpus(t, []string{"5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8 9545824"}, "\n")
+	b, err := OpenBreachCorpus(path)
+	if err != nil {
+		t.Fatalf("OpenBreachCorpus returned %v", err)
+	}
+	defer b.Close()
+	if _, err := b.Count("password"); err == nil || !strings.Contains(err.Error(), "badly formed line") {
+		t.Errorf("Count of a badly formed corpus returned %v", err)
+	}
+}
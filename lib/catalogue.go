@@ -0,0 +1,162 @@
+package lib
+
+import (
+	_ "embed" // needed for the built in policy catalogue
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// builtinPolicies holds the policy catalogue included in the application.
+//
+//go:embed policies.toml
+var builtinPolicies string
+
+// Catalogue holds a collection of named password policies.
+type Catalogue map[string]Policy
+
+// UserPolicyPath returns the location of the policy catalogue file in the
+// user's configuration directory, or an empty string if there is no user
+// configuration directory.
+func UserPolicyPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "passgen", "policies.toml")
+}
+
+// LoadCatalogue returns the built in policy catalogue, updated by the
+// catalogue in the user's configuration directory if it exists, and then
+// by each of the extra catalogue files given.
+func LoadCatalogue(files ...string) (Catalogue, error) {
+	c := Catalogue{}
+	if err := c.Load(strings.NewReader(builtinPolicies), "built in catalogue"); err != nil {
+		return nil, fmt.Errorf("built in policies: %s", err)
+	}
+	if path := UserPolicyPath(); path != "" {
+		if err := c.LoadFile(path); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	for _, path := range files {
+		if err := c.LoadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// LoadFile adds the policies found in the catalogue file at 'path'.
+func (c Catalogue) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := c.Load(f, path); err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+	return nil
+}
+
+// Load adds the policies read from a TOML catalogue, replacing any already
+// held with the same name. The 'source' is recorded as the origin of each
+// rule, so it can be shown when explaining password choices.
+func (c Catalogue) Load(r io.Reader, source string) error {
+	tables, order, err := ParseTOML(r)
+	if err != nil {
+		return err
+	}
+	if len(tables[""]) > 0 {
+		return fmt.Errorf("settings must be inside a [policy-name] table")
+	}
+	for _, name := range order {
+		p := Policy{Name: name, Origin: map[string]string{}}
+		for key, value := range tables[name] {
+			if err := p.set(key, value); err != nil {
+				return fmt.Errorf("[%s] %s", name, err)
+			}
+			p.Origin[key] = source
+		}
+		c[name] = p
+	}
+	return nil
+}
+
+// Names returns the names of the policies in the catalogue in sorted order.
+func (c Catalogue) Names() []string {
+	var names []string
+	for name := range c {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get returns the named policy, or an error listing the policies available
+// if it is not in the catalogue.
+func (c Catalogue) Get(name string) (Policy, error) {
+	p, ok := c[name]
+	if !ok {
+		return p, fmt.Errorf("unknown policy %q - available policies are: %s", name, strings.Join(c.Names(), ", "))
+	}
+	return p, nil
+}
+
+// set updates the policy rule named by 'key' with the value read from a
+// catalogue file.
+func (p *Policy) set(key string, value interface{}) error {
+	ints := map[string]*int{
+		"min_length": &p.MinLength, "max_length": &p.MaxLength,
+		"min_upper": &p.MinUpper, "min_lower": &p.MinLower,
+		"min_digits": &p.MinDigits, "min_symbols": &p.MinSymbols,
+		"min_classes": &p.MinClasses, "max_repeat": &p.MaxRepeat,
+		"max_class_repeat": &p.MaxClassRepeat, "max_sequence": &p.MaxSequence,
+	}
+	if target, ok := ints[key]; ok {
+		n, ok := value.(int64)
+		if !ok || n < 0 {
+			return fmt.Errorf("%s needs a number of zero or more", key)
+		}
+		*target = int(n)
+		return nil
+	}
+	switch key {
+	case "description", "symbols":
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s needs a string", key)
+		}
+		if key == "description" {
+			p.Description = s
+		} else {
+			p.Symbols = s
+		}
+	case "dictionary":
+		b, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("%s needs true or false", key)
+		}
+		p.Dictionary = b
+	case "bad_words":
+		words, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s needs a list of strings", key)
+		}
+		p.BadWords = nil
+		for _, word := range words {
+			s, ok := word.(string)
+			if !ok {
+				return fmt.Errorf("%s needs a list of strings", key)
+			}
+			p.BadWords = append(p.BadWords, s)
+		}
+	default:
+		return fmt.Errorf("unknown rule %q", key)
+	}
+	return nil
+}
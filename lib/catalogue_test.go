@@ -0,0 +1,119 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCatalogueLoad(t *testing.T) {
+	input := `[team]
+description = "our team policy"
+min_length = 14
+min_upper = 1
+symbols = "-_"
+dictionary = true
+bad_words = ["acme", "widget"]
+`
+	c := Catalogue{}
+	if err := c.Load(strings.NewReader(input), "team.toml"); err != nil {
+		t.Fatalf("Load returned %v", err)
+	}
+	p, err := c.Get("team")
+	if err != nil {
+		t.Fatalf("Get returned %v", err)
+	}
+	want := Policy{
+		Name:        "team",
+		Description: "our team policy",
+		MinLength:   14,
+		MinUpper:    1,
+		Symbols:     "-_",
+		Dictionary:  true,
+		BadWords:    []string{"acme", "widget"},
+	}
+	got := p
+	got.Origin = nil
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Load gave %+v, want %+v", got, want)
+	}
+	if p.Origin["min_length"] != "team.toml" {
+		t.Errorf("origin of min_length = %q, want team.toml", p.Origin["min_length"])
+	}
+}
+
+func TestCatalogueLoadErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"min_length = 8", "settings must be inside a [policy-name] table"},
+		{"[a]\nmin_length = -1", "[a] min_length needs a number of zero or more"},
+		{"[a]\nmin_length = \"8\"", "[a] min_length needs a number of zero or more"},
+		{"[a]\ndescription = 8", "[a] description needs a string"},
+		{"[a]\ndictionary = 1", "[a] dictionary needs true or false"},
+		{"[a]\nbad_words = [1]", "[a] bad_words needs a list of strings"},
+		{"[a]\nminimum = 8", `[a] unknown rule "minimum"`},
+	}
+	for _, tt := range tests {
+		err := Catalogue{}.Load(strings.NewReader(tt.input), "test")
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("Load(%q) returned %v, want %s", tt.input, err, tt.want)
+		}
+	}
+}
+
+func TestLoadCatalogue(t *testing.T) {
+	// keep any policies file of the user running the tests out of the way
+	home := t.TempDir()
+	for _, name := range []string{"HOME", "XDG_CONFIG_HOME"} {
+		old, set := os.LookupEnv(name)
+		os.Setenv(name, home)
+		defer func(name string) {
+			if set {
+				os.Setenv(name, old)
+			} else {
+				os.Unsetenv(name)
+			}
+		}(name)
+	}
+	path := filepath.Join(t.TempDir(), "extra.toml")
+	if err := os.WriteFile(path, []byte("[pci-dss]\nmin_length = 16\n\n[extra]\nmin_digits = 2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	c, err := LoadCatalogue(path)
+	if err != nil {
+		t.Fatalf("LoadCatalogue returned %v", err)
+	}
+	if p, _ := c.Get("pci-dss"); p.MinLength != 16 || p.MinDigits != 0 {
+		t.Errorf("pci-dss was not replaced by the extra file: %+v", p)
+	}
+	if p, _ := c.Get("extra"); p.MinDigits != 2 {
+		t.Errorf("extra policy not loaded: %+v", p)
+	}
+	if _, err := c.Get("missing"); err == nil || !strings.Contains(err.Error(), "available policies are: ") {
+		t.Errorf("Get of an unknown policy returned %v", err)
+	}
+}
+
+func TestBuiltinPolicies(t *testing.T) {
+	c := Catalogue{}
+	if err := c.Load(strings.NewReader(builtinPolicies), "built in catalogue"); err != nil {
+		t.Fatalf("the built in catalogue does not load: %v", err)
+	}
+	if len(c) == 0 {
+		t.Fatal("the built in catalogue is empty")
+	}
+	// every built in policy can be met by a generated password
+	for _, name := range c.Names() {
+		p := c[name]
+		if p.Description == "" {
+			t.Errorf("%s: no description", name)
+		}
+		if _, err := p.Plan(3); err != nil {
+			t.Errorf("%s: Plan(3) returned %v", name, err)
+		}
+	}
+}
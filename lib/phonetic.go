@@ -0,0 +1,61 @@
+package lib
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// natoLetters holds the NATO phonetic alphabet, in the order of the letters
+// 'a' to 'z'.
+var natoLetters = []string{
+	"alfa", "bravo", "charlie", "delta", "echo", "foxtrot", "golf", "hotel",
+	"india", "juliett", "kilo", "lima", "mike", "november", "oscar", "papa",
+	"quebec", "romeo", "sierra", "tango", "uniform", "victor", "whiskey",
+	"x-ray", "yankee", "zulu",
+}
+
+// natoDigits holds the words used for the digits '0' to '9'.
+var natoDigits = []string{
+	"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine",
+}
+
+// symbolNames holds the names read out for the symbols used in passwords.
+var symbolNames = map[rune]string{
+	' ': "space", '!': "exclamation", '"': "double-quote", '#': "hash",
+	'$': "dollar", '%': "percent", '&': "ampersand", '\'': "apostrophe",
+	'(': "open-bracket", ')': "close-bracket", '*': "asterisk", '+': "plus",
+	',': "comma", '-': "dash", '.': "dot", '/': "slash", ':': "colon",
+	';': "semicolon", '<': "less-than", '=': "equals", '>': "greater-than",
+	'?': "question", '@': "at", '[': "open-square", '\\': "backslash",
+	']': "close-square", '^': "caret", '_': "underscore", '`': "backtick",
+	'{': "open-brace", '|': "pipe", '}': "close-brace", '~': "tilde",
+}
+
+// Phonetic returns the password spelled out one character at a time, so it
+// can be read aloud without mistakes. Letters use the NATO phonetic
+// alphabet, in lower case, or capitalised and marked '(upper)' for upper
+// case letters. Digits are marked '(digit)' and symbols '(symbol)', and any
+// other character is shown in quotes. For example "OFf-2" is spelled out as
+// "Oscar(upper) Foxtrot(upper) foxtrot dash(symbol) two(digit)".
+func Phonetic(password string) string {
+	var words []string
+	for _, r := range password {
+		switch {
+		case r >= 'a' && r <= 'z':
+			words = append(words, natoLetters[r-'a'])
+		case r >= 'A' && r <= 'Z':
+			word := natoLetters[r-'A']
+			words = append(words, strings.ToUpper(word[:1])+word[1:]+"(upper)")
+		case r >= '0' && r <= '9':
+			words = append(words, natoDigits[r-'0']+"(digit)")
+		case symbolNames[r] != "":
+			words = append(words, symbolNames[r]+"(symbol)")
+		case unicode.IsUpper(r):
+			words = append(words, fmt.Sprintf("%q(upper)", r))
+		default:
+			words = append(words, fmt.Sprintf("%q", r))
+		}
+	}
+	return strings.Join(words, " ")
+}
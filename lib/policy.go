@@ -0,0 +1,342 @@
+package lib
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// DefaultSymbols holds the symbol characters used when a password policy
+// requires symbols but does not restrict which ones may be used. Quotes,
+// back slashes and '$' are left out as they cause trouble in shells and
+// configuration files.
+const DefaultSymbols = "!#%+-.=?@_~"
+
+// Policy holds a set of password composition rules, such as those found in
+// a 'pwquality.conf' file. A zero value for any of the numeric rules means
+// the rule is not enforced.
+type Policy struct {
+	Name           string   // short name of the policy
+	Description    string   // one line description of the policy
+	MinLength      int      // minimum number of characters
+	MaxLength      int      // maximum number of characters
+	MinUpper       int      // minimum number of upper case letters
+	MinLower       int      // minimum number of lower case letters
+	MinDigits      int      // minimum number of digits
+	MinSymbols     int      // minimum number of symbols (any other character)
+	MinClasses     int      // minimum number of the above four classes used
+	MaxRepeat      int      // maximum run of the same character
+	MaxClassRepeat int      // maximum run of characters from the same class
+	MaxSequence    int      // maximum length of a sequence such as 'abc' or '123'
+	Symbols        string   // symbols allowed - empty allows any symbol
+	Dictionary     bool     // reject a password that is just a dictionary word
+	BadWords       []string // words that must not appear in a password
+	// Origin records where a rule came from, keyed on the rule name used
+	// in explanations (such as 'min_length'), so choices can be explained.
+	Origin map[string]string
+	// Notes holds information about settings that were read but can not
+	// be applied to a generated password.
+	Notes []string
+}
+
+// Plan describes how a password should be generated so it satisfies a
+// Policy. Reasons holds a line of explanation for each choice made.
+type Plan struct {
+	Words     int      // number of three letter words to use
+	Mixed     bool     // randomly upper case some of the letters
+	Digits    int      // number of digits to include
+	Symbols   int      // number of symbols to include
+	SymbolSet string   // symbols to choose from
+	Separate  bool     // place the digits and symbols between the words
+	Reasons   []string // explanation of each choice made
+}
+
+// rule returns a description of a rule and its value, including where the
+// rule came from if known, for use in explanations.
+func (p Policy) rule(name string, value interface{}) string {
+	desc := fmt.Sprintf("%s = %v", name, value)
+	if origin, ok := p.Origin[name]; ok && origin != "" {
+		desc += " (" + origin + ")"
+	}
+	return desc
+}
+
+// Plan works out how to generate a password of at least 'words' three
+// letter words that satisfies the policy. An error is returned if the
+// policy can not be met by a generated password.
+func (p Policy) Plan(words int) (Plan, error) {
+	plan := Plan{Words: words, SymbolSet: p.symbolSet()}
+
+	if p.MinUpper > 0 {
+		plan.Mixed = true
+		plan.Reasons = append(plan.Reasons, p.rule("min_upper", p.MinUpper)+": using mixed case letters")
+	}
+	if p.MinDigits > 0 {
+		plan.Digits = p.MinDigits
+		plan.Reasons = append(plan.Reasons, fmt.Sprintf("%s: adding %d digit(s)", p.rule("min_digits", p.MinDigits), p.MinDigits))
+	}
+	if p.MinSymbols > 0 {
+		plan.Symbols = p.MinSymbols
+		plan.Reasons = append(plan.Reasons, fmt.Sprintf("%s: adding %d symbol(s)", p.rule("min_symbols", p.MinSymbols), p.MinSymbols))
+	}
+
+	// count the character classes already in use - lower case letters are
+	// always included, then add upper case, digits and symbols in turn
+	// until the minimum number of classes is reached
+	classes := 1
+	for _, used := range []bool{plan.Mixed, plan.Digits > 0, plan.Symbols > 0} {
+		if used {
+			classes++
+		}
+	}
+	if classes < p.MinClasses && !plan.Mixed {
+		plan.Mixed = true
+		classes++
+		plan.Reasons = append(plan.Reasons, p.rule("min_classes", p.MinClasses)+": using mixed case letters")
+	}
+	if classes < p.MinClasses && plan.Digits == 0 {
+		plan.Digits = 1
+		classes++
+		plan.Reasons = append(plan.Reasons, p.rule("min_classes", p.MinClasses)+": adding a digit")
+	}
+	if classes < p.MinClasses && plan.Symbols == 0 {
+		plan.Symbols = 1
+		classes++
+		plan.Reasons = append(plan.Reasons, p.rule("min_classes", p.MinClasses)+": adding a symbol")
+	}
+	if classes < p.MinClasses {
+		return plan, fmt.Errorf("%s can not be met as only four character classes exist", p.rule("min_classes", p.MinClasses))
+	}
+
+	// the letters of a three letter word are all in the same class, so
+	// short class runs need separators between words and mixed case
+	if p.MaxClassRepeat > 0 {
+		plan.Separate = true
+		plan.Reasons = append(plan.Reasons, p.rule("max_class_repeat", p.MaxClassRepeat)+": placing digits or symbols between words")
+		if p.MaxClassRepeat < 3 && !plan.Mixed {
+			plan.Mixed = true
+			plan.Reasons = append(plan.Reasons, p.rule("max_class_repeat", p.MaxClassRepeat)+": using mixed case letters")
+		}
+	}
+	if p.Dictionary && !plan.Separate {
+		plan.Separate = true
+		plan.Reasons = append(plan.Reasons, p.rule("dictionary", p.Dictionary)+": placing digits or symbols between words")
+	}
+
+	// enough letters are needed for the minimum lower and upper case counts
+	if letters := p.MinUpper + p.MinLower; plan.Words*3 < letters {
+		plan.Words = (letters + 2) / 3
+		plan.Reasons = append(plan.Reasons, fmt.Sprintf("min_upper + min_lower = %d: using %d words", letters, plan.Words))
+	}
+
+	// separated words need one digit or symbol between each pair of words
+	// - so make sure there are enough of them. Recalculated below if the
+	// number of words changes to meet the minimum length.
+	separators := func() {
+		if plan.Separate && plan.Digits+plan.Symbols < plan.Words-1 {
+			plan.Digits = plan.Words - 1 - plan.Symbols
+		}
+	}
+	separators()
+
+	if p.MinLength > plan.Length() {
+		for p.MinLength > plan.Length() {
+			plan.Words++
+			separators()
+		}
+		plan.Reasons = append(plan.Reasons, fmt.Sprintf("%s: using %d words for a length of %d", p.rule("min_length", p.MinLength), plan.Words, plan.Length()))
+	}
+	if p.MaxLength > 0 && plan.Length() > p.MaxLength {
+		return plan, fmt.Errorf("%s is too short for the %d characters needed to meet the other rules", p.rule("max_length", p.MaxLength), plan.Length())
+	}
+	if plan.Separate && plan.Words > 1 {
+		plan.Reasons = append(plan.Reasons, fmt.Sprintf("separating %d words needs %d digits or symbols: using %d digit(s) and %d symbol(s)", plan.Words, plan.Words-1, plan.Digits, plan.Symbols))
+	}
+	return plan, nil
+}
+
+// symbolSet returns the symbols to use when generating a password. These
+// are the default symbols allowed by the policy, or if none of those are
+// allowed, all of the symbols the policy allows.
+func (p Policy) symbolSet() string {
+	if p.Symbols == "" {
+		return DefaultSymbols
+	}
+	var set strings.Builder
+	for _, c := range DefaultSymbols {
+		if strings.ContainsRune(p.Symbols, c) {
+			set.WriteRune(c)
+		}
+	}
+	if set.Len() == 0 {
+		return p.Symbols
+	}
+	return set.String()
+}
+
+// Length returns the number of characters in a password generated by the plan.
+func (plan Plan) Length() int {
+	return plan.Words*3 + plan.Digits + plan.Symbols
+}
+
+// Entropy returns the bits of entropy of a password generated from the
+// plan: each word is chosen at random from Passmap, each letter may be
+// upper or lower case if mixed, and each digit and symbol is random. The
+// passwords rejected for breaking a policy rule are not taken into account,
+// so this is an upper limit.
+func (plan Plan) Entropy() float64 {
+	bits := float64(plan.Words) * math.Log2(float64(len(Passmap)))
+	if plan.Mixed {
+		bits += float64(plan.Words * 3)
+	}
+	bits += float64(plan.Digits) * math.Log2(10)
+	if n := len([]rune(plan.SymbolSet)); plan.Symbols > 0 && n > 1 {
+		bits += float64(plan.Symbols) * math.Log2(float64(n))
+	}
+	return bits
+}
+
+// Check tests the password against each rule in the policy, and returns a
+// description of every rule that is broken. An empty slice is returned if
+// the password meets the policy.
+func (p Policy) Check(password string) []string {
+	var broken []string
+	var upper, lower, digits, symbols int
+	for _, c := range password {
+		switch {
+		case unicode.IsUpper(c):
+			upper++
+		case unicode.IsLower(c):
+			lower++
+		case unicode.IsDigit(c):
+			digits++
+		default:
+			symbols++
+			if p.Symbols != "" && !strings.ContainsRune(p.Symbols, c) {
+				broken = append(broken, fmt.Sprintf("%s: the character %q is not allowed", p.rule("symbols", p.Symbols), c))
+			}
+		}
+	}
+	length := len([]rune(password))
+
+	if p.MinLength > 0 && length < p.MinLength {
+		broken = append(broken, fmt.Sprintf("%s: has %d characters", p.rule("min_length", p.MinLength), length))
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		broken = append(broken, fmt.Sprintf("%s: has %d characters", p.rule("max_length", p.MaxLength), length))
+	}
+	if upper < p.MinUpper {
+		broken = append(broken, fmt.Sprintf("%s: has %d upper case letters", p.rule("min_upper", p.MinUpper), upper))
+	}
+	if lower < p.MinLower {
+		broken = append(broken, fmt.Sprintf("%s: has %d lower case letters", p.rule("min_lower", p.MinLower), lower))
+	}
+	if digits < p.MinDigits {
+		broken = append(broken, fmt.Sprintf("%s: has %d digits", p.rule("min_digits", p.MinDigits), digits))
+	}
+	if symbols < p.MinSymbols {
+		broken = append(broken, fmt.Sprintf("%s: has %d symbols", p.rule("min_symbols", p.MinSymbols), symbols))
+	}
+	classes := 0
+	for _, count := range []int{upper, lower, digits, symbols} {
+		if count > 0 {
+			classes++
+		}
+	}
+	if classes < p.MinClasses {
+		broken = append(broken, fmt.Sprintf("%s: uses %d character classes", p.rule("min_classes", p.MinClasses), classes))
+	}
+	if run := longestRun(password, func(a, b rune) bool { return a == b }); p.MaxRepeat > 0 && run > p.MaxRepeat {
+		broken = append(broken, fmt.Sprintf("%s: repeats a character %d times in a row", p.rule("max_repeat", p.MaxRepeat), run))
+	}
+	if run := longestRun(password, func(a, b rune) bool { return charClass(a) == charClass(b) }); p.MaxClassRepeat > 0 && run > p.MaxClassRepeat {
+		broken = append(broken, fmt.Sprintf("%s: has %d characters of the same class in a row", p.rule("max_class_repeat", p.MaxClassRepeat), run))
+	}
+	if run := longestSequence(password); p.MaxSequence > 0 && run > p.MaxSequence {
+		broken = append(broken, fmt.Sprintf("%s: has a sequence of %d characters", p.rule("max_sequence", p.MaxSequence), run))
+	}
+	if p.Dictionary && IsWord(strings.TrimFunc(password, func(c rune) bool { return !unicode.IsLetter(c) })) {
+		broken = append(broken, p.rule("dictionary", p.Dictionary)+": is a single dictionary word")
+	}
+	lowered := strings.ToLower(password)
+	for _, word := range p.BadWords {
+		if word != "" && strings.Contains(lowered, strings.ToLower(word)) {
+			broken = append(broken, fmt.Sprintf("%s: contains %q", p.rule("bad_words", strings.Join(p.BadWords, " ")), word))
+		}
+	}
+	return broken
+}
+
+// IsWord returns true if the string given is one of the three letter
+// words held in Passmap, ignoring case.
+func IsWord(s string) bool {
+	s = strings.ToLower(s)
+	for _, word := range Passmap {
+		if word == s {
+			return true
+		}
+	}
+	return false
+}
+
+// charClass returns the class of the character given as one of: 'u' upper
+// case letter, 'l' lower case letter, 'd' digit, or 'o' other.
+func charClass(c rune) rune {
+	switch {
+	case unicode.IsUpper(c):
+		return 'u'
+	case unicode.IsLower(c):
+		return 'l'
+	case unicode.IsDigit(c):
+		return 'd'
+	}
+	return 'o'
+}
+
+// longestRun returns the length of the longest run of characters in the
+// string, where each character and the one before it are matched by the
+// function 'same'.
+func longestRun(s string, same func(a, b rune) bool) int {
+	longest, run := 0, 0
+	var prev rune
+	for i, c := range []rune(s) {
+		if i > 0 && same(prev, c) {
+			run++
+		} else {
+			run = 1
+		}
+		if run > longest {
+			longest = run
+		}
+		prev = c
+	}
+	return longest
+}
+
+// longestSequence returns the length of the longest run of characters in
+// the string that go up or down by one each time, such as 'abc' or '321'.
+func longestSequence(s string) int {
+	longest, run, step := 0, 0, rune(0)
+	runes := []rune(s)
+	for i := range runes {
+		if i > 0 {
+			diff := runes[i] - runes[i-1]
+			if (diff == 1 || diff == -1) && (run == 1 || diff == step) {
+				run++
+				step = diff
+			} else {
+				run = 1
+				if diff == 1 || diff == -1 {
+					run, step = 2, diff
+				}
+			}
+		} else {
+			run = 1
+		}
+		if run > longest {
+			longest = run
+		}
+	}
+	return longest
+}
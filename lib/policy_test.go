@@ -0,0 +1,148 @@
+package lib
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPlan(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+		words  int
+		want   Plan // only the choices are compared, not the reasons
+		err    bool
+	}{
+		{"no rules", Policy{}, 3, Plan{Words: 3}, false},
+		{"minimum counts", Policy{MinUpper: 1, MinDigits: 2, MinSymbols: 1}, 3, Plan{Words: 3, Mixed: true, Digits: 2, Symbols: 1}, false},
+		{"four classes", Policy{MinClasses: 4}, 3, Plan{Words: 3, Mixed: true, Digits: 1, Symbols: 1}, false},
+		{"five classes", Policy{MinClasses: 5}, 3, Plan{}, true},
+		{"short class runs", Policy{MaxClassRepeat: 2}, 3, Plan{Words: 3, Mixed: true, Digits: 2, Separate: true}, false},
+		{"dictionary", Policy{Dictionary: true}, 4, Plan{Words: 4, Digits: 3, Separate: true}, false},
+		{"minimum length", Policy{MinLength: 20}, 3, Plan{Words: 7}, false},
+		{"minimum length separated", Policy{MinLength: 20, Dictionary: true}, 3, Plan{Words: 6, Digits: 5, Separate: true}, false},
+		{"maximum length", Policy{MaxLength: 8}, 3, Plan{}, true},
+		{"letter counts", Policy{MinUpper: 5, MinLower: 5}, 1, Plan{Words: 4, Mixed: true}, false},
+	}
+	for _, tt := range tests {
+		plan, err := tt.policy.Plan(tt.words)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%s: Plan(%d) returned no error", tt.name, tt.words)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: Plan(%d) returned %v", tt.name, tt.words, err)
+			continue
+		}
+		got := Plan{Words: plan.Words, Mixed: plan.Mixed, Digits: plan.Digits, Symbols: plan.Symbols, Separate: plan.Separate}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Plan(%d) = %+v, want %+v", tt.name, tt.words, got, tt.want)
+		}
+		if tt.policy.MinLength > 0 && plan.Length() < tt.policy.MinLength {
+			t.Errorf("%s: plan length %d is below the minimum of %d", tt.name, plan.Length(), tt.policy.MinLength)
+		}
+	}
+}
+
+func TestPlanSymbolSet(t *testing.T) {
+	tests := []struct {
+		allowed string
+		want    string
+	}{
+		{"", DefaultSymbols},
+		{"#$%", "#%"},
+		{"$^", "$^"},
+	}
+	for _, tt := range tests {
+		plan, err := Policy{MinSymbols: 1, Symbols: tt.allowed}.Plan(3)
+		if err != nil {
+			t.Fatalf("Plan returned %v", err)
+		}
+		if plan.SymbolSet != tt.want {
+			t.Errorf("symbols allowed %q gave the symbol set %q, want %q", tt.allowed, plan.SymbolSet, tt.want)
+		}
+	}
+}
+
+func TestPlanEntropy(t *testing.T) {
+	word := math.Log2(float64(len(Passmap)))
+	tests := []struct {
+		plan Plan
+		want float64
+	}{
+		{Plan{Words: 3}, 3 * word},
+		{Plan{Words: 3, Mixed: true}, 3*word + 9},
+		{Plan{Words: 2, Digits: 2, Symbols: 1, SymbolSet: "!#"}, 2*word + 2*math.Log2(10) + 1},
+		{Plan{Words: 2, Symbols: 1, SymbolSet: "!"}, 2 * word},
+	}
+	for _, tt := range tests {
+		if got := tt.plan.Entropy(); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%+v.Entropy() = %f, want %f", tt.plan, got, tt.want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		policy   Policy
+		password string
+		broken   []string // a part of each rule broken, in order
+	}{
+		{Policy{}, "cat dog pig", nil},
+		{Policy{MinLength: 10}, "catdogpig", []string{"min_length = 10: has 9 characters"}},
+		{Policy{MinLength: 10, Origin: map[string]string{"min_length": "minlen = 10"}}, "catdogpig", []string{"min_length = 10 (minlen = 10): has 9 characters"}},
+		{Policy{MaxLength: 8}, "catdogpig", []string{"max_length = 8: has 9 characters"}},
+		{Policy{MinUpper: 1, MinLower: 1, MinDigits: 1, MinSymbols: 1}, "Cat1-dog", nil},
+		{Policy{MinUpper: 1, MinLower: 1, MinDigits: 1, MinSymbols: 1}, "catdog", []string{"min_upper", "min_digits", "min_symbols"}},
+		{Policy{MinClasses: 3}, "catDog", []string{"min_classes = 3: uses 2 character classes"}},
+		{Policy{MinClasses: 3}, "catDog7", nil},
+		{Policy{MaxRepeat: 2}, "caat", nil},
+		{Policy{MaxRepeat: 2}, "caaat", []string{"max_repeat = 2: repeats a character 3 times in a row"}},
+		{Policy{MaxClassRepeat: 3}, "cat1dog", nil},
+		{Policy{MaxClassRepeat: 3}, "catdog", []string{"max_class_repeat = 3: has 6 characters of the same class in a row"}},
+		{Policy{MaxSequence: 3}, "xabcx", nil},
+		{Policy{MaxSequence: 3}, "xabcdx", []string{"max_sequence = 3: has a sequence of 4 characters"}},
+		{Policy{MaxSequence: 3}, "x4321", []string{"max_sequence = 3: has a sequence of 4 characters"}},
+		{Policy{Symbols: "-"}, "cat-dog", nil},
+		{Policy{Symbols: "-"}, "cat_dog", []string{"the character '_' is not allowed"}},
+		{Policy{Dictionary: true}, "Cat!", []string{"dictionary = true: is a single dictionary word"}},
+		{Policy{Dictionary: true}, "catdog", nil},
+		{Policy{BadWords: []string{"acme"}}, "xACMEx", []string{"bad_words = acme: contains \"acme\""}},
+	}
+	for _, tt := range tests {
+		broken := tt.policy.Check(tt.password)
+		if len(broken) != len(tt.broken) {
+			t.Errorf("Check(%q) with %+v = %q, want %d broken rule(s)", tt.password, tt.policy, broken, len(tt.broken))
+			continue
+		}
+		for i := range broken {
+			if !strings.Contains(broken[i], tt.broken[i]) {
+				t.Errorf("Check(%q) broken rule %d = %q, want it to contain %q", tt.password, i, broken[i], tt.broken[i])
+			}
+		}
+	}
+}
+
+func TestLongestSequence(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{"", 0},
+		{"a", 1},
+		{"ace", 1},
+		{"abc", 3},
+		{"cba", 3},
+		{"abcba", 3},
+		{"xy9876z", 4},
+	}
+	for _, tt := range tests {
+		if got := longestSequence(tt.s); got != tt.want {
+			t.Errorf("longestSequence(%q) = %d, want %d", tt.s, got, tt.want)
+		}
+	}
+}
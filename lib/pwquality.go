@@ -0,0 +1,141 @@
+package lib
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PWQualityPath is the default location of the pam_pwquality settings file
+// used on most Linux systems.
+const PWQualityPath = "/etc/security/pwquality.conf"
+
+// ReadPWQuality reads the pam_pwquality settings file found at 'path' and
+// returns the password policy it describes. When the default path is used
+// any '*.conf' files in the 'pwquality.conf.d' directory are read after it,
+// in name order, in the same way as pam_pwquality does.
+func ReadPWQuality(path string) (Policy, error) {
+	// start with the pam_pwquality built in defaults
+	p := Policy{
+		Name:        "pwquality",
+		Description: "rules read from " + path,
+		MinLength:   8,
+		Dictionary:  true,
+		Origin:      map[string]string{"min_length": "minlen default", "dictionary": "dictcheck default"},
+	}
+	files := []string{path}
+	if path == PWQualityPath {
+		extra, err := filepath.Glob(path + ".d/*.conf")
+		if err != nil {
+			return p, err
+		}
+		sort.Strings(extra)
+		files = append(files, extra...)
+	}
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			return p, err
+		}
+		err = parsePWQuality(f, name, &p)
+		f.Close()
+		if err != nil {
+			return p, err
+		}
+	}
+	return p, nil
+}
+
+// parsePWQuality reads 'key = value' settings from r into the policy. The
+// name of the file being read is used in any error messages.
+func parsePWQuality(r io.Reader, name string, p *Policy) error {
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(text, "#"); i >= 0 {
+			text = strings.TrimSpace(text[:i])
+		}
+		if text == "" {
+			continue
+		}
+		// boolean options such as 'enforce_for_root' are given without a value
+		key, value := text, ""
+		if i := strings.Index(text, "="); i >= 0 {
+			key, value = strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:])
+		}
+		setting := key
+		if value != "" {
+			setting += " = " + value
+		}
+
+		switch key {
+		case "minlen", "dcredit", "ucredit", "lcredit", "ocredit", "minclass",
+			"maxrepeat", "maxclassrepeat", "maxsequence", "dictcheck":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("%s:%d: %s needs a number, not %q", name, line, key, value)
+			}
+			applyPWQuality(p, key, n, setting)
+		case "badwords":
+			p.BadWords = strings.Fields(value)
+			p.Origin["bad_words"] = setting
+		case "difok", "usercheck", "usersubstr", "gecoscheck":
+			p.Notes = append(p.Notes, setting+": not applied - needs the old password or user account details")
+		case "enforcing", "retry", "enforce_for_root", "local_users_only", "dictpath":
+			p.Notes = append(p.Notes, setting+": not applied - does not affect the password content")
+		default:
+			p.Notes = append(p.Notes, setting+": unknown setting ignored")
+		}
+	}
+	return scanner.Err()
+}
+
+// applyPWQuality sets the policy rule matching a numeric pam_pwquality
+// setting. A negative credit is the minimum number of characters needed
+// from that class, while a positive credit only counts towards 'minlen'
+// so needs no rule, as generated passwords always meet 'minlen' in full.
+func applyPWQuality(p *Policy, key string, n int, setting string) {
+	set := func(rule string, target *int, value int) {
+		*target = value
+		p.Origin[rule] = setting
+	}
+	credit := func(rule string, target *int) {
+		if n < 0 {
+			set(rule, target, -n)
+		} else {
+			set(rule, target, 0)
+		}
+	}
+	switch key {
+	case "minlen":
+		// pam_pwquality will not accept a minimum length below six
+		if n < 6 {
+			n = 6
+		}
+		set("min_length", &p.MinLength, n)
+	case "dcredit":
+		credit("min_digits", &p.MinDigits)
+	case "ucredit":
+		credit("min_upper", &p.MinUpper)
+	case "lcredit":
+		credit("min_lower", &p.MinLower)
+	case "ocredit":
+		credit("min_symbols", &p.MinSymbols)
+	case "minclass":
+		set("min_classes", &p.MinClasses, n)
+	case "maxrepeat":
+		set("max_repeat", &p.MaxRepeat, n)
+	case "maxclassrepeat":
+		set("max_class_repeat", &p.MaxClassRepeat, n)
+	case "maxsequence":
+		set("max_sequence", &p.MaxSequence, n)
+	case "dictcheck":
+		p.Dictionary = n != 0
+		p.Origin["dictionary"] = setting
+	}
+}
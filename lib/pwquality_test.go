@@ -0,0 +1,116 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePWQuality(t *testing.T) {
+	conf := `# pam_pwquality settings
+minlen = 12
+dcredit = -2
+ucredit = -1
+lcredit = 1
+ocredit=-1    # a comment after a setting
+minclass = 3
+maxrepeat = 2
+maxclassrepeat = 4
+maxsequence = 3
+dictcheck = 0
+badwords = acme Widget
+
+enforce_for_root
+difok = 5
+frobnicate = 1
+`
+	p := Policy{MinLength: 8, Dictionary: true, Origin: map[string]string{}}
+	if err := parsePWQuality(strings.NewReader(conf), "test.conf", &p); err != nil {
+		t.Fatalf("parsePWQuality returned %v", err)
+	}
+	want := Policy{
+		MinLength:      12,
+		MinDigits:      2,
+		MinUpper:       1,
+		MinLower:       0,
+		MinSymbols:     1,
+		MinClasses:     3,
+		MaxRepeat:      2,
+		MaxClassRepeat: 4,
+		MaxSequence:    3,
+		Dictionary:     false,
+		BadWords:       []string{"acme", "Widget"},
+	}
+	got := p
+	got.Origin, got.Notes = nil, nil
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parsePWQuality gave %+v, want %+v", got, want)
+	}
+	origins := map[string]string{
+		"min_length":  "minlen = 12",
+		"min_digits":  "dcredit = -2",
+		"min_lower":   "lcredit = 1",
+		"min_symbols": "ocredit = -1",
+		"dictionary":  "dictcheck = 0",
+		"bad_words":   "badwords = acme Widget",
+	}
+	for rule, origin := range origins {
+		if p.Origin[rule] != origin {
+			t.Errorf("origin of %s = %q, want %q", rule, p.Origin[rule], origin)
+		}
+	}
+	notes := []string{
+		"enforce_for_root: not applied - does not affect the password content",
+		"difok = 5: not applied - needs the old password or user account details",
+		"frobnicate = 1: unknown setting ignored",
+	}
+	if !reflect.DeepEqual(p.Notes, notes) {
+		t.Errorf("notes = %q, want %q", p.Notes, notes)
+	}
+}
+
+func TestParsePWQualityMinimumLength(t *testing.T) {
+	p := Policy{Origin: map[string]string{}}
+	if err := parsePWQuality(strings.NewReader("minlen = 4\n"), "test.conf", &p); err != nil {
+		t.Fatalf("parsePWQuality returned %v", err)
+	}
+	if p.MinLength != 6 {
+		t.Errorf("minlen = 4 gave a minimum length of %d, want 6", p.MinLength)
+	}
+}
+
+func TestParsePWQualityErrors(t *testing.T) {
+	tests := []struct {
+		conf string
+		want string
+	}{
+		{"minlen = twelve\n", "test.conf:1: minlen needs a number, not \"twelve\""},
+		{"# comment\n\ndcredit =\n", "test.conf:3: dcredit needs a number, not \"\""},
+	}
+	for _, tt := range tests {
+		p := Policy{Origin: map[string]string{}}
+		err := parsePWQuality(strings.NewReader(tt.conf), "test.conf", &p)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("parsePWQuality(%q) returned %v, want %s", tt.conf, err, tt.want)
+		}
+	}
+}
+
+func TestReadPWQuality(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pwquality.conf")
+	if err := os.WriteFile(path, []byte("minlen = 14\nucredit = -1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	p, err := ReadPWQuality(path)
+	if err != nil {
+		t.Fatalf("ReadPWQuality returned %v", err)
+	}
+	if p.Name != "pwquality" || p.MinLength != 14 || p.MinUpper != 1 || !p.Dictionary {
+		t.Errorf("ReadPWQuality gave %+v", p)
+	}
+	if _, err := ReadPWQuality(filepath.Join(t.TempDir(), "missing.conf")); err == nil {
+		t.Errorf("ReadPWQuality of a missing file returned no error")
+	}
+}
@@ -0,0 +1,555 @@
+package lib
+
+import (
+	"fmt"
+	"strings"
+)
+
+// QRLevel is the error correction level of a QR code: how much of the code
+// can be damaged or hidden and still be read.
+type QRLevel int
+
+// the error correction levels, from least to most able to recover damage
+const (
+	QRLevelL QRLevel = iota // recovers about 7% of the code
+	QRLevelM                // recovers about 15%
+	QRLevelQ                // recovers about 25%
+	QRLevelH                // recovers about 30%
+)
+
+// qrFormatBits holds the bits used for each error correction level in the
+// format information of a QR code.
+var qrFormatBits = [4]int{1, 0, 3, 2}
+
+// qrECCPerBlock holds the number of error correction codewords in each
+// block, for each level and version (index 0 is not used).
+var qrECCPerBlock = [4][41]int{
+	{0, 7, 10, 15, 20, 26, 18, 20, 24, 30, 18, 20, 24, 26, 30, 22, 24, 28, 30, 28, 28, 28, 28, 30, 30, 26, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{0, 10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26, 26, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28},
+	{0, 13, 22, 18, 26, 18, 24, 18, 22, 20, 24, 28, 26, 24, 20, 30, 24, 28, 28, 26, 30, 28, 30, 30, 30, 30, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{0, 17, 28, 22, 16, 22, 28, 26, 26, 24, 28, 24, 28, 22, 24, 24, 30, 28, 28, 26, 28, 30, 24, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+}
+
+// qrBlocks holds the number of error correction blocks, for each level and
+// version (index 0 is not used).
+var qrBlocks = [4][41]int{
+	{0, 1, 1, 1, 1, 1, 2, 2, 2, 2, 4, 4, 4, 4, 4, 6, 6, 6, 6, 7, 8, 8, 9, 9, 10, 12, 12, 12, 13, 14, 15, 16, 17, 18, 19, 19, 20, 21, 22, 24, 25},
+	{0, 1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16, 17, 17, 18, 20, 21, 23, 25, 26, 28, 29, 31, 33, 35, 37, 38, 40, 43, 45, 47, 49},
+	{0, 1, 1, 2, 2, 4, 4, 6, 6, 8, 8, 8, 10, 12, 16, 12, 17, 16, 18, 21, 20, 23, 23, 25, 27, 29, 34, 34, 35, 38, 40, 43, 45, 48, 51, 53, 56, 59, 62, 65, 68},
+	{0, 1, 1, 2, 4, 4, 4, 5, 6, 8, 8, 11, 11, 16, 16, 18, 16, 19, 21, 25, 25, 25, 34, 30, 32, 35, 37, 40, 42, 45, 48, 51, 54, 57, 60, 63, 66, 70, 74, 77, 81},
+}
+
+// QRCode holds the modules of a QR code: Size modules across and down, where
+// true is a dark module. The quiet zone around the code is not included.
+type QRCode struct {
+	Version int
+	Size    int
+	modules [][]bool
+	isFunc  [][]bool // modules used by the fixed patterns, which are not masked
+}
+
+// EncodeQR returns a QR code holding the data, encoded in byte mode with the
+// error correction level given, using the smallest version it fits in.
+func EncodeQR(data []byte, level QRLevel) (*QRCode, error) {
+	if level < QRLevelL || level > QRLevelH {
+		return nil, fmt.Errorf("unknown QR code error correction level %d", level)
+	}
+	version := 0
+	for v := 1; v <= 40; v++ {
+		if 4+qrCountBits(v)+8*len(data) <= qrDataCodewords(v, level)*8 {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, fmt.Errorf("%d bytes is too much data for a QR code", len(data))
+	}
+
+	// the bit stream: byte mode, the count of bytes, then the bytes
+	var bits qrBits
+	bits.add(0x4, 4)
+	bits.add(len(data), qrCountBits(version))
+	for _, b := range data {
+		bits.add(int(b), 8)
+	}
+	// end with up to four zero bits, fill to a whole byte, and then pad
+	// with the two bytes set by the standard in turn
+	capacity := qrDataCodewords(version, level) * 8
+	terminator := capacity - len(bits)
+	if terminator > 4 {
+		terminator = 4
+	}
+	bits.add(0, terminator)
+	bits.add(0, (8-len(bits)%8)%8)
+	for pad := 0xEC; len(bits) < capacity; pad ^= 0xEC ^ 0x11 {
+		bits.add(pad, 8)
+	}
+	codewords := make([]byte, len(bits)/8)
+	for i, bit := range bits {
+		if bit {
+			codewords[i/8] |= 1 << uint(7-i%8)
+		}
+	}
+
+	q := &QRCode{Version: version, Size: version*4 + 17}
+	q.modules = make([][]bool, q.Size)
+	q.isFunc = make([][]bool, q.Size)
+	for y := range q.modules {
+		q.modules[y] = make([]bool, q.Size)
+		q.isFunc[y] = make([]bool, q.Size)
+	}
+	q.drawFunctionPatterns(level)
+	q.drawCodewords(qrAddECC(codewords, version, level))
+
+	// use the mask that gives the lowest penalty - the least confusing code
+	// for a reader
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		q.applyMask(mask)
+		q.drawFormatBits(level, mask)
+		if penalty := q.penalty(); bestPenalty < 0 || penalty < bestPenalty {
+			best, bestPenalty = mask, penalty
+		}
+		// masking twice restores the modules
+		q.applyMask(mask)
+	}
+	q.applyMask(best)
+	q.drawFormatBits(level, best)
+	return q, nil
+}
+
+// Dark returns true if the module at column x and row y is dark. Modules
+// outside the code, in the quiet zone, are light.
+func (q *QRCode) Dark(x, y int) bool {
+	if x < 0 || y < 0 || x >= q.Size || y >= q.Size {
+		return false
+	}
+	return q.modules[y][x]
+}
+
+// qrBits holds a stream of bits, most significant first.
+type qrBits []bool
+
+// add appends the lowest n bits of the value.
+func (b *qrBits) add(value, n int) {
+	for i := n - 1; i >= 0; i-- {
+		*b = append(*b, (value>>uint(i))&1 == 1)
+	}
+}
+
+// qrCountBits returns the number of bits used for the count of bytes in
+// byte mode.
+func qrCountBits(version int) int {
+	if version <= 9 {
+		return 8
+	}
+	return 16
+}
+
+// qrRawModules returns the number of modules that hold data and error
+// correction codewords, after the fixed patterns, format and version
+// information are taken away. Some versions have a few remainder bits.
+func qrRawModules(version int) int {
+	result := (16*version+128)*version + 64
+	if version >= 2 {
+		align := version/7 + 2
+		result -= (25*align-10)*align - 55
+		if version >= 7 {
+			result -= 36
+		}
+	}
+	return result
+}
+
+// qrDataCodewords returns the number of data codewords a QR code holds.
+func qrDataCodewords(version int, level QRLevel) int {
+	return qrRawModules(version)/8 - qrECCPerBlock[level][version]*qrBlocks[level][version]
+}
+
+// qrAddECC splits the data codewords into blocks, adds the Reed-Solomon
+// error correction codewords to each block, and interleaves the blocks.
+func qrAddECC(data []byte, version int, level QRLevel) []byte {
+	numBlocks := qrBlocks[level][version]
+	eccLen := qrECCPerBlock[level][version]
+	raw := qrRawModules(version) / 8
+	numShort := numBlocks - raw%numBlocks
+	shortLen := raw / numBlocks
+
+	divisor := rsDivisor(eccLen)
+	var blocks [][]byte
+	k := 0
+	for i := 0; i < numBlocks; i++ {
+		n := shortLen - eccLen
+		if i >= numShort {
+			n++
+		}
+		block := append([]byte{}, data[k:k+n]...)
+		k += n
+		ecc := rsRemainder(block, divisor)
+		if i < numShort {
+			// a gap, so all blocks line up when they are interleaved
+			block = append(block, 0)
+		}
+		blocks = append(blocks, append(block, ecc...))
+	}
+
+	var result []byte
+	for i := range blocks[0] {
+		for j, block := range blocks {
+			if i != shortLen-eccLen || j >= numShort {
+				result = append(result, block[i])
+			}
+		}
+	}
+	return result
+}
+
+// rsMultiply returns the product of two numbers in the Galois field
+// GF(2^8) used by QR codes.
+func rsMultiply(x, y byte) byte {
+	var z int
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int((y>>uint(i))&1) * int(x)
+	}
+	return byte(z)
+}
+
+// rsDivisor returns the generator polynomial for the number of error
+// correction codewords given, without its leading term.
+func rsDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = rsMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = rsMultiply(root, 0x02)
+	}
+	return result
+}
+
+// rsRemainder returns the error correction codewords for the data.
+func rsRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, coef := range divisor {
+			result[i] ^= rsMultiply(coef, factor)
+		}
+	}
+	return result
+}
+
+// set sets a module used by the fixed patterns.
+func (q *QRCode) set(x, y int, dark bool) {
+	q.modules[y][x] = dark
+	q.isFunc[y][x] = true
+}
+
+// drawFunctionPatterns draws the finder, timing and alignment patterns, and
+// the version information, and reserves the modules for the format bits.
+func (q *QRCode) drawFunctionPatterns(level QRLevel) {
+	for i := 0; i < q.Size; i++ {
+		q.set(6, i, i%2 == 0)
+		q.set(i, 6, i%2 == 0)
+	}
+	q.drawFinder(3, 3)
+	q.drawFinder(q.Size-4, 3)
+	q.drawFinder(3, q.Size-4)
+
+	align := q.alignmentPositions()
+	last := len(align) - 1
+	for i := range align {
+		for j := range align {
+			// the corners with finder patterns have no alignment pattern
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			q.drawAlignment(align[i], align[j])
+		}
+	}
+
+	q.drawFormatBits(level, 0)
+	q.drawVersion()
+}
+
+// drawFinder draws a finder pattern, and the light separator around it,
+// centred on the module given.
+func (q *QRCode) drawFinder(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			xx, yy := x+dx, y+dy
+			if xx < 0 || yy < 0 || xx >= q.Size || yy >= q.Size {
+				continue
+			}
+			dist := qrMax(qrAbs(dx), qrAbs(dy))
+			q.set(xx, yy, dist != 2 && dist != 4)
+		}
+	}
+}
+
+// drawAlignment draws an alignment pattern centred on the module given.
+func (q *QRCode) drawAlignment(x, y int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			q.set(x+dx, y+dy, qrMax(qrAbs(dx), qrAbs(dy)) != 1)
+		}
+	}
+}
+
+// alignmentPositions returns the rows and columns of the centres of the
+// alignment patterns, in increasing order.
+func (q *QRCode) alignmentPositions() []int {
+	if q.Version == 1 {
+		return nil
+	}
+	num := q.Version/7 + 2
+	step := (q.Version*8 + num*3 + 5) / (num*4 - 4) * 2
+	result := make([]int, num)
+	result[0] = 6
+	for i, pos := num-1, q.Size-7; i > 0; i, pos = i-1, pos-step {
+		result[i] = pos
+	}
+	return result
+}
+
+// drawFormatBits draws both copies of the format information, which holds
+// the error correction level and mask, protected by a BCH code.
+func (q *QRCode) drawFormatBits(level QRLevel, mask int) {
+	data := qrFormatBits[level]<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return (bits>>uint(i))&1 == 1 }
+
+	// the copy around the top left finder pattern
+	for i := 0; i <= 5; i++ {
+		q.set(8, i, bit(i))
+	}
+	q.set(8, 7, bit(6))
+	q.set(8, 8, bit(7))
+	q.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		q.set(14-i, 8, bit(i))
+	}
+	// the copy split between the other two finder patterns
+	for i := 0; i < 8; i++ {
+		q.set(q.Size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		q.set(8, q.Size-15+i, bit(i))
+	}
+	// the module that is always dark
+	q.set(8, q.Size-8, true)
+}
+
+// drawVersion draws both copies of the version information, used from
+// version 7 up.
+func (q *QRCode) drawVersion() {
+	if q.Version < 7 {
+		return
+	}
+	rem := q.Version
+	for i := 0; i < 12; i++ {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+	}
+	bits := q.Version<<12 | rem
+	for i := 0; i < 18; i++ {
+		dark := (bits>>uint(i))&1 == 1
+		a, b := q.Size-11+i%3, i/3
+		q.set(a, b, dark)
+		q.set(b, a, dark)
+	}
+}
+
+// drawCodewords places the codewords in the modules not used by the fixed
+// patterns, in pairs of columns zigzagging up and down from the bottom
+// right corner.
+func (q *QRCode) drawCodewords(codewords []byte) {
+	i := 0
+	for right := q.Size - 1; right >= 1; right -= 2 {
+		// the vertical timing pattern is skipped
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < q.Size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = q.Size - 1 - vert
+				}
+				if !q.isFunc[y][x] && i < len(codewords)*8 {
+					q.modules[y][x] = (codewords[i/8]>>uint(7-i%8))&1 == 1
+					i++
+				}
+			}
+		}
+	}
+}
+
+// applyMask flips the modules not used by the fixed patterns where the mask
+// pattern is true. Applying the same mask twice undoes it.
+func (q *QRCode) applyMask(mask int) {
+	for y := 0; y < q.Size; y++ {
+		for x := 0; x < q.Size; x++ {
+			var flip bool
+			switch mask {
+			case 0:
+				flip = (x+y)%2 == 0
+			case 1:
+				flip = y%2 == 0
+			case 2:
+				flip = x%3 == 0
+			case 3:
+				flip = (x+y)%3 == 0
+			case 4:
+				flip = (x/3+y/2)%2 == 0
+			case 5:
+				flip = x*y%2+x*y%3 == 0
+			case 6:
+				flip = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				flip = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if flip && !q.isFunc[y][x] {
+				q.modules[y][x] = !q.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty scores the modules with the four rules of the standard: runs of
+// the same colour, blocks of the same colour, patterns that look like a
+// finder pattern, and an uneven balance of dark and light.
+func (q *QRCode) penalty() int {
+	score := 0
+	n := q.Size
+	get := func(x, y int, across bool) bool {
+		if across {
+			return q.modules[y][x]
+		}
+		return q.modules[x][y]
+	}
+	finder := []bool{true, false, true, true, true, false, true}
+	for _, across := range []bool{true, false} {
+		for line := 0; line < n; line++ {
+			// runs of five or more modules of the same colour
+			run := 1
+			for i := 1; i <= n; i++ {
+				if i < n && get(i, line, across) == get(i-1, line, across) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					score += 3 + run - 5
+				}
+				run = 1
+			}
+			// the finder pattern, with four light modules on either side
+			for i := 0; i+7 <= n; i++ {
+				match := true
+				for k, dark := range finder {
+					if get(i+k, line, across) != dark {
+						match = false
+						break
+					}
+				}
+				if !match {
+					continue
+				}
+				if qrLight(q, i-4, i, line, across) || qrLight(q, i+7, i+11, line, across) {
+					score += 40
+				}
+			}
+		}
+	}
+	// blocks of two by two modules of the same colour
+	dark := 0
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			if q.modules[y][x] {
+				dark++
+			}
+			if x+1 < n && y+1 < n {
+				c := q.modules[y][x]
+				if q.modules[y][x+1] == c && q.modules[y+1][x] == c && q.modules[y+1][x+1] == c {
+					score += 3
+				}
+			}
+		}
+	}
+	// each 5% the share of dark modules is away from half
+	total := n * n
+	score += qrAbs(dark*20-total*10) / total * 10
+	return score
+}
+
+// qrLight returns true if the modules from 'from' up to 'to' on the line are
+// all light. Modules outside the code, in the quiet zone, are light.
+func qrLight(q *QRCode, from, to, line int, across bool) bool {
+	for i := from; i < to; i++ {
+		x, y := i, line
+		if !across {
+			x, y = line, i
+		}
+		if q.Dark(x, y) {
+			return false
+		}
+	}
+	return true
+}
+
+// HalfBlocks returns the QR code drawn with Unicode half block characters,
+// so each line of text holds two rows of modules, with a quiet zone of the
+// given number of modules around it. The colours are set with ANSI escape
+// sequences - black on white - so the code can be read on both light and
+// dark terminals.
+func (q *QRCode) HalfBlocks(quiet int) string {
+	var b strings.Builder
+	for y := -quiet; y < q.Size+quiet; y += 2 {
+		current := ""
+		for x := -quiet; x < q.Size+quiet; x++ {
+			// the upper half block is drawn in the foreground colour, and the
+			// lower half shows the background colour
+			fg, bg := "97", "107"
+			if q.Dark(x, y) {
+				fg = "30"
+			}
+			if q.Dark(x, y+1) {
+				bg = "40"
+			}
+			// the colours are only set when they change
+			if style := "\x1b[" + fg + ";" + bg + "m"; style != current {
+				b.WriteString(style)
+				current = style
+			}
+			b.WriteString("▀")
+		}
+		b.WriteString("\x1b[0m\n")
+	}
+	return b.String()
+}
+
+func qrAbs(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+func qrMax(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,402 @@
+package lib
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// gfExp returns alpha to the power n in GF(2^8), worked out from scratch so
+// the tests do not depend on rsMultiply.
+func gfExp(n int) byte {
+	x := 1
+	for i := 0; i < n; i++ {
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11D
+		}
+	}
+	return byte(x)
+}
+
+func TestRSDivisor(t *testing.T) {
+	// the generator polynomial for seven error correction codewords, as
+	// powers of alpha, from the QR code standard
+	exponents := []int{87, 229, 146, 149, 238, 102, 21}
+	got := rsDivisor(7)
+	for i, e := range exponents {
+		if got[i] != gfExp(e) {
+			t.Errorf("rsDivisor(7)[%d] = %d, want alpha^%d = %d", i, got[i], e, gfExp(e))
+		}
+	}
+}
+
+func TestRSRemainder(t *testing.T) {
+	// the worked 1-M example of 'HELLO WORLD' from the Thonky QR code tutorial
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if got := rsRemainder(data, rsDivisor(10)); !bytes.Equal(got, want) {
+		t.Errorf("rsRemainder = %v, want %v", got, want)
+	}
+}
+
+// formatStrings holds the 15 bit format information for each level and
+// mask, from the table in the QR code standard.
+var formatStrings = map[QRLevel][8]string{
+	QRLevelL: {"111011111000100", "111001011110011", "111110110101010", "111100010011101", "110011000101111", "110001100011000", "110110001000001", "110100101110110"},
+	QRLevelM: {"101010000010010", "101000100100101", "101111001111100", "101101101001011", "100010111111001", "100000011001110", "100111110010111", "100101010100000"},
+	QRLevelQ: {"011010101011111", "011000001101000", "011111100110001", "011101000000110", "010010010110100", "010000110000011", "010111011011010", "010101111101101"},
+	QRLevelH: {"001011010001001", "001001110111110", "001110011100111", "001100111010000", "000011101100010", "000001001010101", "000110100001100", "000100000111011"},
+}
+
+// readFormat returns both copies of the format information of the code.
+func readFormat(dark func(x, y int) bool, size int) (first, second int) {
+	bit := func(v bool, i int) int {
+		if v {
+			return 1 << uint(i)
+		}
+		return 0
+	}
+	for i := 0; i <= 5; i++ {
+		first |= bit(dark(8, i), i)
+	}
+	first |= bit(dark(8, 7), 6) | bit(dark(8, 8), 7) | bit(dark(7, 8), 8)
+	for i := 9; i < 15; i++ {
+		first |= bit(dark(14-i, 8), i)
+	}
+	for i := 0; i < 8; i++ {
+		second |= bit(dark(size-1-i, 8), i)
+	}
+	for i := 8; i < 15; i++ {
+		second |= bit(dark(8, size-15+i), i)
+	}
+	return first, second
+}
+
+func TestFormatBits(t *testing.T) {
+	q, err := EncodeQR([]byte("abc"), QRLevelL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for level, masks := range formatStrings {
+		for mask, s := range masks {
+			want, _ := strconv.ParseInt(s, 2, 32)
+			q.drawFormatBits(level, mask)
+			first, second := readFormat(q.Dark, q.Size)
+			if first != int(want) || second != int(want) {
+				t.Errorf("level %d mask %d: format bits %015b and %015b, want %s", level, mask, first, second, s)
+			}
+		}
+	}
+}
+
+func TestVersionBits(t *testing.T) {
+	// the 18 bit version information from the table in the QR code standard
+	tests := []struct {
+		version int
+		bits    string
+	}{
+		{7, "000111110010010100"},
+		{8, "001000010110111100"},
+		{21, "010101011010000011"},
+		{40, "101000110001101001"},
+	}
+	for _, tt := range tests {
+		q := &QRCode{Version: tt.version, Size: tt.version*4 + 17}
+		q.modules = make([][]bool, q.Size)
+		q.isFunc = make([][]bool, q.Size)
+		for y := range q.modules {
+			q.modules[y] = make([]bool, q.Size)
+			q.isFunc[y] = make([]bool, q.Size)
+		}
+		q.drawVersion()
+		var below, right int
+		for i := 0; i < 18; i++ {
+			a, b := q.Size-11+i%3, i/3
+			if q.Dark(b, a) {
+				below |= 1 << uint(i)
+			}
+			if q.Dark(a, b) {
+				right |= 1 << uint(i)
+			}
+		}
+		want, _ := strconv.ParseInt(tt.bits, 2, 32)
+		if below != int(want) || right != int(want) {
+			t.Errorf("version %d: version bits %018b and %018b, want %s", tt.version, below, right, tt.bits)
+		}
+	}
+}
+
+func TestEncodeQRVersion(t *testing.T) {
+	// the most bytes each version holds in byte mode, from the capacity
+	// table in the QR code standard
+	tests := []struct {
+		level   QRLevel
+		version int
+		bytes   int
+	}{
+		{QRLevelL, 1, 17},
+		{QRLevelM, 1, 14},
+		{QRLevelQ, 1, 11},
+		{QRLevelH, 1, 7},
+		{QRLevelL, 2, 32},
+		{QRLevelM, 5, 84},
+		{QRLevelL, 9, 230},
+		{QRLevelL, 10, 271},
+		{QRLevelH, 20, 382},
+		{QRLevelL, 40, 2953},
+		{QRLevelM, 40, 2331},
+		{QRLevelQ, 40, 1663},
+		{QRLevelH, 40, 1273},
+	}
+	for _, tt := range tests {
+		q, err := EncodeQR(make([]byte, tt.bytes), tt.level)
+		if err != nil || q.Version != tt.version || q.Size != tt.version*4+17 {
+			t.Errorf("EncodeQR of %d bytes at level %d gave %+v, %v, want version %d", tt.bytes, tt.level, q, err, tt.version)
+			continue
+		}
+		q, err = EncodeQR(make([]byte, tt.bytes+1), tt.level)
+		switch {
+		case tt.version == 40 && err == nil:
+			t.Errorf("EncodeQR of %d bytes at level %d returned no error", tt.bytes+1, tt.level)
+		case tt.version < 40 && (err != nil || q.Version != tt.version+1):
+			t.Errorf("EncodeQR of %d bytes at level %d gave %+v, %v, want version %d", tt.bytes+1, tt.level, q, err, tt.version+1)
+		}
+	}
+	if _, err := EncodeQR([]byte("abc"), QRLevel(4)); err == nil {
+		t.Errorf("EncodeQR with an unknown level returned no error")
+	}
+}
+
+// qrMaskAt returns whether the mask pattern flips the module, using the
+// conditions as written in the QR code standard, with i the row and j the
+// column.
+func qrMaskAt(mask, i, j int) bool {
+	switch mask {
+	case 0:
+		return (i+j)%2 == 0
+	case 1:
+		return i%2 == 0
+	case 2:
+		return j%3 == 0
+	case 3:
+		return (i+j)%3 == 0
+	case 4:
+		return (i/2+j/3)%2 == 0
+	case 5:
+		return (i*j)%2+(i*j)%3 == 0
+	case 6:
+		return ((i*j)%2+(i*j)%3)%2 == 0
+	}
+	return ((i+j)%2+(i*j)%3)%2 == 0
+}
+
+// unmasked returns the modules of the code given by 'dark', with the mask
+// read from its format information removed from the data modules.
+func unmasked(q *QRCode, dark func(x, y int) bool) (QRLevel, [][]bool) {
+	format, _ := readFormat(dark, q.Size)
+	format ^= 0x5412
+	level := map[int]QRLevel{1: QRLevelL, 0: QRLevelM, 3: QRLevelQ, 2: QRLevelH}[format>>13]
+	mask := format >> 10 & 7
+	modules := make([][]bool, q.Size)
+	for y := range modules {
+		modules[y] = make([]bool, q.Size)
+		for x := range modules[y] {
+			modules[y][x] = dark(x, y)
+			if !q.isFunc[y][x] && qrMaskAt(mask, y, x) {
+				modules[y][x] = !modules[y][x]
+			}
+		}
+	}
+	return level, modules
+}
+
+// decodeQR reads the data back from the code, checking the error
+// correction codewords of every block are correct.
+func decodeQR(t *testing.T, q *QRCode) []byte {
+	level, modules := unmasked(q, q.Dark)
+	// read the codewords in the zigzag order, from the bottom right
+	var bits []bool
+	for right := q.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < q.Size; vert++ {
+			for j := 0; j < 2; j++ {
+				x, y := right-j, vert
+				if (right+1)&2 == 0 {
+					y = q.Size - 1 - vert
+				}
+				if !q.isFunc[y][x] {
+					bits = append(bits, modules[y][x])
+				}
+			}
+		}
+	}
+	raw := make([]byte, qrRawModules(q.Version)/8)
+	for i := range raw {
+		for b := 0; b < 8; b++ {
+			if bits[i*8+b] {
+				raw[i] |= 1 << uint(7-b)
+			}
+		}
+	}
+
+	// undo the interleaving: the data codewords of each block in turn, with
+	// the longer blocks last, then the error correction codewords
+	numBlocks := qrBlocks[level][q.Version]
+	eccLen := qrECCPerBlock[level][q.Version]
+	numLong := len(raw) % numBlocks
+	shortData := len(raw)/numBlocks - eccLen
+	blocks := make([][]byte, numBlocks)
+	k := 0
+	for i := 0; i < shortData+1; i++ {
+		for b := range blocks {
+			if i < shortData || b >= numBlocks-numLong {
+				blocks[b] = append(blocks[b], raw[k])
+				k++
+			}
+		}
+	}
+	var data []byte
+	for b := range blocks {
+		data = append(data, blocks[b]...)
+	}
+	for i := 0; i < eccLen; i++ {
+		for b := range blocks {
+			blocks[b] = append(blocks[b], raw[k])
+			k++
+		}
+	}
+	// a block with correct error correction has no syndromes: it is zero
+	// at each of the powers of alpha used to make the generator
+	for b, block := range blocks {
+		for i := 0; i < eccLen; i++ {
+			var sum byte
+			for _, c := range block {
+				sum = rsMultiply(sum, gfExp(i)) ^ c
+			}
+			if sum != 0 {
+				t.Errorf("block %d has a syndrome of %d at alpha^%d", b, sum, i)
+			}
+		}
+	}
+
+	// byte mode, then the count of bytes, then the bytes
+	get := func(from, n int) int {
+		v := 0
+		for i := from; i < from+n; i++ {
+			v = v<<1 | int(data[i/8]>>uint(7-i%8)&1)
+		}
+		return v
+	}
+	if mode := get(0, 4); mode != 0x4 {
+		t.Fatalf("mode %04b, want byte mode 0100", mode)
+	}
+	count := get(4, qrCountBits(q.Version))
+	result := make([]byte, count)
+	for i := range result {
+		result[i] = byte(get(4+qrCountBits(q.Version)+i*8, 8))
+	}
+	return result
+}
+
+func TestEncodeQRRoundTrip(t *testing.T) {
+	tests := []struct {
+		data  string
+		level QRLevel
+	}{
+		{"", QRLevelL},
+		{"cat dog pig", QRLevelM},
+		{"WIFI:T:WPA;S:home;P:cat-dog-pig-owl;;", QRLevelQ},
+		{"vim-pub-rai-eth-hah-gal", QRLevelH},
+		{strings.Repeat("cat dog pig ", 40), QRLevelM},
+		{strings.Repeat("\x00\xff", 600), QRLevelL},
+	}
+	for _, tt := range tests {
+		q, err := EncodeQR([]byte(tt.data), tt.level)
+		if err != nil {
+			t.Fatalf("EncodeQR(%q) returned %v", tt.data, err)
+		}
+		if got := decodeQR(t, q); string(got) != tt.data {
+			t.Errorf("EncodeQR(%q) at version %d decodes to %q", tt.data, q.Version, got)
+		}
+	}
+}
+
+func TestEncodeQRGolden(t *testing.T) {
+	// codes made by the skip2/go-qrcode encoder. An encoder may choose any
+	// mask, so the codes are compared with their masks removed.
+	tests := []struct {
+		file  string
+		data  string
+		level QRLevel
+	}{
+		{"abc-L.txt", "abc", QRLevelL},
+		{"password-M.txt", "cat dog pig", QRLevelM},
+		{"phrase-M.txt", "home;cat-dog-pig-owl;hen-ape-elk;", QRLevelM},
+		{"url-Q.txt", "https://github.com/wiremoons/passgen", QRLevelQ},
+		{"words-H.txt", "vim-pub-rai-eth-hah-gal", QRLevelH},
+		{"owl-L.txt", strings.Repeat("owl", 60), QRLevelL},
+	}
+	for _, tt := range tests {
+		golden, err := ioutil.ReadFile(filepath.Join("testdata", "qr", tt.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows := strings.Fields(string(golden))
+		q, err := EncodeQR([]byte(tt.data), tt.level)
+		if err != nil {
+			t.Fatalf("%s: EncodeQR returned %v", tt.file, err)
+		}
+		if len(rows) != q.Size {
+			t.Errorf("%s: size %d, want %d", tt.file, q.Size, len(rows))
+			continue
+		}
+		_, want := unmasked(q, func(x, y int) bool { return rows[y][x] == '#' })
+		level, got := unmasked(q, q.Dark)
+		if level != tt.level {
+			t.Errorf("%s: format holds level %d, want %d", tt.file, level, tt.level)
+		}
+		for y := range got {
+			for x := range got[y] {
+				if got[y][x] != want[y][x] && !isFormat(q, x, y) {
+					t.Errorf("%s: module %d,%d differs", tt.file, x, y)
+				}
+			}
+		}
+	}
+}
+
+// isFormat returns true if the module holds format information, which
+// changes with the mask chosen.
+func isFormat(q *QRCode, x, y int) bool {
+	return (y == 8 && (x <= 8 || x >= q.Size-8)) || (x == 8 && (y <= 8 || y >= q.Size-8))
+}
+
+func TestHalfBlocks(t *testing.T) {
+	q, err := EncodeQR([]byte("abc"), QRLevelL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(q.HalfBlocks(4), "\n"), "\n")
+	// two rows of modules to each line of text
+	if want := (q.Size + 8 + 1) / 2; len(lines) != want {
+		t.Errorf("HalfBlocks gave %d lines, want %d", len(lines), want)
+	}
+	for i, line := range lines {
+		if n := strings.Count(line, "▀"); n != q.Size+8 {
+			t.Errorf("line %d has %d half blocks, want %d", i, n, q.Size+8)
+		}
+		if !strings.HasSuffix(line, "\x1b[0m") {
+			t.Errorf("line %d does not reset the colours", i)
+		}
+	}
+	// the first line is all quiet zone, so white on white
+	if lines[0] != "\x1b[97;107m"+strings.Repeat("▀", q.Size+8)+"\x1b[0m" {
+		t.Errorf("first line = %q", lines[0])
+	}
+}
@@ -0,0 +1,57 @@
+package lib
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+)
+
+// PNG writes the QR code as a black and white PNG image, with each module
+// drawn as a square 'scale' pixels across, and a quiet zone of the given
+// number of modules around it.
+func (q *QRCode) PNG(w io.Writer, scale, quiet int) error {
+	side := (q.Size + 2*quiet) * scale
+	img := image.NewPaletted(image.Rect(0, 0, side, side), color.Palette{color.White, color.Black})
+	for py := 0; py < side; py++ {
+		for px := 0; px < side; px++ {
+			if q.Dark(px/scale-quiet, py/scale-quiet) {
+				img.SetColorIndex(px, py, 1)
+			}
+		}
+	}
+	return png.Encode(w, img)
+}
+
+// SVG writes the QR code as an SVG image, with each module drawn as a
+// square 'scale' units across, and a quiet zone of the given number of
+// modules around it.
+func (q *QRCode) SVG(w io.Writer, scale, quiet int) error {
+	side := q.Size + 2*quiet
+	_, err := fmt.Fprintf(w, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\" shape-rendering=\"crispEdges\">\n<rect width=\"100%%\" height=\"100%%\" fill=\"#fff\"/>\n<path fill=\"#000\" d=\"%s\"/>\n</svg>\n",
+		side*scale, side*scale, side, side, q.SVGPath(quiet))
+	return err
+}
+
+// SVGPath returns the SVG path data that draws the dark modules, one unit
+// across each, moved right and down by the quiet zone given. Each run of
+// dark modules in a row is drawn as a single rectangle.
+func (q *QRCode) SVGPath(quiet int) string {
+	var b strings.Builder
+	for y := 0; y < q.Size; y++ {
+		for x := 0; x < q.Size; x++ {
+			if !q.Dark(x, y) {
+				continue
+			}
+			run := 1
+			for q.Dark(x+run, y) {
+				run++
+			}
+			fmt.Fprintf(&b, "M%d %dh%dv1h-%dz", x+quiet, y+quiet, run, run)
+			x += run - 1
+		}
+	}
+	return b.String()
+}
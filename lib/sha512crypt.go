@@ -0,0 +1,129 @@
+package lib
+
+import (
+	"crypto/sha512"
+	"strconv"
+)
+
+// CryptAlphabet holds the characters used by crypt for salts and hashes, in
+// the order of their values.
+const CryptAlphabet = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// SHA512CryptRounds is the number of rounds used by SHA-512 crypt when none
+// is given in the hash, as in /etc/shadow by default.
+const SHA512CryptRounds = 5000
+
+// sha512CryptOrder holds the order the bytes of the final digest are
+// written in, three at a time, by SHA-512 crypt.
+var sha512CryptOrder = [][3]int{
+	{0, 21, 42}, {22, 43, 1}, {44, 2, 23}, {3, 24, 45}, {25, 46, 4}, {47, 5, 26},
+	{6, 27, 48}, {28, 49, 7}, {50, 8, 29}, {9, 30, 51}, {31, 52, 10}, {53, 11, 32},
+	{12, 33, 54}, {34, 55, 13}, {56, 14, 35}, {15, 36, 57}, {37, 58, 16}, {59, 17, 38},
+	{18, 39, 60}, {40, 61, 19}, {62, 20, 41},
+}
+
+// SHA512Crypt returns the password hashed with SHA-512 crypt, the '$6$'
+// format used in /etc/shadow, as described by Ulrich Drepper in "Unix crypt
+// using SHA-256 and SHA-512". The salt is cut to 16 characters, and the
+// rounds kept between 1,000 and 999,999,999 - the number of rounds is only
+// written in the hash if it is not the default.
+func SHA512Crypt(password, salt string, rounds int) string {
+	if len(salt) > 16 {
+		salt = salt[:16]
+	}
+	if rounds < 1000 {
+		rounds = 1000
+	}
+	if rounds > 999999999 {
+		rounds = 999999999
+	}
+	p, s := []byte(password), []byte(salt)
+
+	// digest B is of the password, salt and password
+	h := sha512.New()
+	h.Write(p)
+	h.Write(s)
+	h.Write(p)
+	b := h.Sum(nil)
+
+	// digest A is of the password, the salt, digest B repeated to the length
+	// of the password, and then digest B or the password for each bit of the
+	// length of the password
+	h.Reset()
+	h.Write(p)
+	h.Write(s)
+	h.Write(repeatBytes(b, len(p)))
+	for n := len(p); n > 0; n >>= 1 {
+		if n&1 == 1 {
+			h.Write(b)
+		} else {
+			h.Write(p)
+		}
+	}
+	a := h.Sum(nil)
+
+	// the P and S sequences, from the password and salt repeated
+	h.Reset()
+	for i := 0; i < len(p); i++ {
+		h.Write(p)
+	}
+	pSeq := repeatBytes(h.Sum(nil), len(p))
+	h.Reset()
+	for i := 0; i < 16+int(a[0]); i++ {
+		h.Write(s)
+	}
+	sSeq := repeatBytes(h.Sum(nil), len(s))
+
+	// the rounds, each hashing the result of the last
+	c := a
+	for i := 0; i < rounds; i++ {
+		h.Reset()
+		if i%2 == 1 {
+			h.Write(pSeq)
+		} else {
+			h.Write(c)
+		}
+		if i%3 != 0 {
+			h.Write(sSeq)
+		}
+		if i%7 != 0 {
+			h.Write(pSeq)
+		}
+		if i%2 == 1 {
+			h.Write(c)
+		} else {
+			h.Write(pSeq)
+		}
+		c = h.Sum(nil)
+	}
+
+	result := "$6$"
+	if rounds != SHA512CryptRounds {
+		result += "rounds=" + strconv.Itoa(rounds) + "$"
+	}
+	result += salt + "$"
+	for _, order := range sha512CryptOrder {
+		result += cryptBase64(uint(c[order[0]])<<16|uint(c[order[1]])<<8|uint(c[order[2]]), 4)
+	}
+	return result + cryptBase64(uint(c[63]), 2)
+}
+
+// repeatBytes returns the bytes repeated, and cut to the length given.
+func repeatBytes(b []byte, length int) []byte {
+	result := make([]byte, 0, length+len(b))
+	for len(result) < length {
+		result = append(result, b...)
+	}
+	return result[:length]
+}
+
+// cryptBase64 returns the lowest 6*n bits of the value as n characters of
+// the crypt alphabet, lowest bits first.
+func cryptBase64(value uint, n int) string {
+	var out []byte
+	for i := 0; i < n; i++ {
+		out = append(out, CryptAlphabet[value&0x3f])
+		value >>= 6
+	}
+	return string(out)
+}
@@ -0,0 +1,53 @@
+package lib
+
+import "testing"
+
+func TestSHA512Crypt(t *testing.T) {
+	// the SHA-512 test vectors from Ulrich Drepper's "Unix crypt using
+	// SHA-256 and SHA-512". The third is given there with 'rounds=5000$',
+	// which is only written here when the rounds are not the default.
+	tests := []struct {
+		password string
+		salt     string
+		rounds   int
+		want     string
+	}{
+		{"Hello world!", "saltstring", SHA512CryptRounds,
+			"$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1"},
+		{"Hello world!", "saltstringsaltstring", 10000,
+			"$6$rounds=10000$saltstringsaltst$OW1/O6BYHV6BcXZu8QVeXbDWra3Oeqh0sbHbbMCVNSnCM/UrjmM0Dp8vOuZeHBy/YTBmSK6H9qs/y3RnOaw5v."},
+		{"This is just a test", "toolongsaltstring", 5000,
+			"$6$toolongsaltstrin$lQ8jolhgVRVhY4b5pZKaysCLi0QBxGoNeKQzQ3glMhwllF7oGDZxUhx1yxdYcz/e1JSbq3y6JMxxl8audkUEm0"},
+		{"a very much longer text to encrypt.  This one even stretches over morethan one line.", "anotherlongsaltstring", 1400,
+			"$6$rounds=1400$anotherlongsalts$POfYwTEok97VWcjxIiSOjiykti.o/pQs.wPvMxQ6Fm7I6IoYN3CmLs66x9t0oSwbtEW7o7UmJEiDwGqd8p4ur1"},
+		{"we have a short salt string but not a short password", "short", 77777,
+			"$6$rounds=77777$short$WuQyW2YR.hBNpjjRhpYD/ifIw05xdfeEyQoMxIXbkvr0gge1a1x3yRULJ5CCaUeOxFmtlcGZelFl5CxtgfiAc0"},
+		{"a short string", "asaltof16chars..", 123456,
+			"$6$rounds=123456$asaltof16chars..$BtCwjqMJGx5hrJhZywWvt0RLE8uZ4oPwcelCjmw2kSYu.Ec6ycULevoBK25fs2xXgMNrCzIMVcgEJAstJeonj1"},
+		{"the minimum number is still observed", "roundstoolow", 10,
+			"$6$rounds=1000$roundstoolow$kUMsbe306n21p9R.FRkW3IGn.S9NPN0x50YhH1xhLsPuWGsUSklZt58jaTfF4ZEQpyUNGc0dqbpBYYBaHHrsX."},
+	}
+	for _, tt := range tests {
+		if got := SHA512Crypt(tt.password, tt.salt, tt.rounds); got != tt.want {
+			t.Errorf("SHA512Crypt(%q, %q, %d) = %s, want %s", tt.password, tt.salt, tt.rounds, got, tt.want)
+		}
+	}
+}
+
+func TestCryptBase64(t *testing.T) {
+	tests := []struct {
+		value uint
+		n     int
+		want  string
+	}{
+		{0, 2, ".."},
+		{63, 1, "z"},
+		{64, 2, "./"},
+		{0xffffff, 4, "zzzz"},
+	}
+	for _, tt := range tests {
+		if got := cryptBase64(tt.value, tt.n); got != tt.want {
+			t.Errorf("cryptBase64(%#x, %d) = %q, want %q", tt.value, tt.n, got, tt.want)
+		}
+	}
+}
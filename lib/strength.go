@@ -0,0 +1,655 @@
+package lib
+
+import (
+	_ "embed" // needed for the built in list of common passwords
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// commonList holds common passwords and words, most popular first.
+//
+//go:embed common.txt
+var commonList string
+
+// Match holds a part of a password found to follow a guessable pattern.
+type Match struct {
+	Pattern string  // name of the pattern, such as 'dictionary' or 'date'
+	Token   string  // the part of the password matched
+	Start   int     // position of the first character matched
+	End     int     // position after the last character matched
+	Guesses float64 // estimated guesses needed to find the token
+	Detail  string  // extra information such as the dictionary word found
+}
+
+// Strength holds the result of estimating how hard a password is to guess.
+// Matches holds the patterns found, in password order, that together give
+// the lowest number of guesses.
+type Strength struct {
+	Matches []Match
+	Guesses float64
+}
+
+// CrackTime holds an estimate of the time needed to guess a password in
+// one attack scenario.
+type CrackTime struct {
+	Scenario string
+	Seconds  float64
+}
+
+// Log10 returns the estimated guesses as a power of ten.
+func (s Strength) Log10() float64 {
+	return math.Log10(s.Guesses)
+}
+
+// CrackTimes returns the time needed to guess the password in a number of
+// attack scenarios, from a throttled online attack to an offline attack on
+// a fast hash. On average a password is found after half the guesses.
+func (s Strength) CrackTimes() []CrackTime {
+	scenarios := []struct {
+		name   string
+		perSec float64
+	}{
+		{"online attack, throttled (100 per hour)", 100.0 / 3600},
+		{"online attack, unthrottled (10 per second)", 10},
+		{"offline attack, slow hash (10 thousand per second)", 1e4},
+		{"offline attack, fast hash (10 billion per second)", 1e10},
+	}
+	var times []CrackTime
+	for _, sc := range scenarios {
+		times = append(times, CrackTime{sc.name, s.Guesses / 2 / sc.perSec})
+	}
+	return times
+}
+
+// FormatDuration returns a number of seconds as a rough human readable
+// duration such as '3 hours' or 'centuries'.
+func FormatDuration(seconds float64) string {
+	units := []struct {
+		name string
+		size float64
+	}{
+		{"year", 365.25 * 24 * 3600},
+		{"month", 30.44 * 24 * 3600},
+		{"day", 24 * 3600},
+		{"hour", 3600},
+		{"minute", 60},
+		{"second", 1},
+	}
+	switch {
+	case seconds < 1:
+		return "less than a second"
+	case seconds >= 100*units[0].size:
+		return "centuries"
+	}
+	for _, unit := range units {
+		if seconds >= unit.size {
+			n := math.Round(seconds / unit.size)
+			if n == 1 {
+				return "1 " + unit.name
+			}
+			return fmt.Sprintf("%.0f %ss", n, unit.name)
+		}
+	}
+	return "less than a second"
+}
+
+// dictionary maps each known word to its rank, where a lower rank is a
+// more likely guess. The three letter words in Passmap are all equally
+// likely, so share a rank of the number of words in the pool.
+var dictionary map[string]int
+
+// buildDictionary fills the dictionary on first use.
+func buildDictionary() {
+	if dictionary != nil {
+		return
+	}
+	dictionary = make(map[string]int)
+	for _, word := range Passmap {
+		dictionary[word] = len(Passmap)
+	}
+	rank := 0
+	for _, line := range strings.Split(commonList, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rank++
+		if old, ok := dictionary[line]; !ok || rank < old {
+			dictionary[line] = rank
+		}
+	}
+}
+
+// leet holds the letters commonly replaced by look alike characters.
+var leet = map[rune][]rune{
+	'4': {'a'}, '@': {'a'}, '8': {'b'}, '(': {'c'}, '3': {'e'}, '6': {'g'},
+	'1': {'i', 'l'}, '!': {'i'}, '|': {'i', 'l'}, '0': {'o'}, '$': {'s'},
+	'5': {'s'}, '7': {'t'}, '+': {'t'}, '2': {'z'},
+}
+
+// MaxStrengthLength is the most characters of a password scored by
+// EstimateStrength. As in zxcvbn, any characters past this are ignored, as
+// the time taken grows quickly with the length. Ignoring them can only
+// lower the estimate.
+const MaxStrengthLength = 100
+
+// EstimateStrength estimates how many guesses an attacker needs to find
+// the password. The password is searched for dictionary words (with case
+// changes and leet substitutions), keyboard walks, repeats, sequences and
+// dates. The combination of these patterns, and brute force guessing of
+// any remaining characters, needing the fewest guesses is used.
+func EstimateStrength(password string) Strength {
+	buildDictionary()
+	runes := []rune(password)
+	if len(runes) > MaxStrengthLength {
+		runes = runes[:MaxStrengthLength]
+	}
+	return estimate(runes, map[string]float64{})
+}
+
+// estimate finds the patterns in the password for EstimateStrength. The
+// guesses for the repeated part of each repeat are estimated the same
+// way, and kept in memo so each is only worked out once.
+func estimate(runes []rune, memo map[string]float64) Strength {
+	var matches []Match
+	matches = append(matches, dictionaryMatches(runes)...)
+	matches = append(matches, spatialMatches(runes)...)
+	matches = append(matches, repeatMatches(runes, memo)...)
+	matches = append(matches, sequenceMatches(runes)...)
+	matches = append(matches, dateMatches(runes)...)
+	return bestMatches(runes, matches)
+}
+
+// bestMatches finds the lowest guess combination of matches covering the
+// password, filling any gaps with brute force guessing. Each extra match
+// in a combination multiplies the guesses by the number of ways the
+// patterns could be ordered.
+func bestMatches(runes []rune, matches []Match) Strength {
+	n := len(runes)
+	if n == 0 {
+		return Strength{Guesses: 1}
+	}
+	// best[i] holds the lowest log10 guesses for the first i characters,
+	// and from[i] the match used to reach position i
+	best := make([]float64, n+1)
+	from := make([]*Match, n+1)
+	for i := 1; i <= n; i++ {
+		best[i] = math.Inf(1)
+	}
+	byEnd := map[int][]int{}
+	for i, m := range matches {
+		byEnd[m.End] = append(byEnd[m.End], i)
+	}
+	for end := 1; end <= n; end++ {
+		// brute force guessing of a single character
+		c := runes[end-1]
+		brute := &Match{Pattern: "bruteforce", Token: string(c), Start: end - 1, End: end, Guesses: float64(cardinality(c))}
+		best[end] = best[end-1] + math.Log10(brute.Guesses)
+		from[end] = brute
+		for _, i := range byEnd[end] {
+			m := &matches[i]
+			guesses := math.Max(m.Guesses, 50)
+			if cost := best[m.Start] + math.Log10(guesses); cost < best[end] {
+				best[end] = cost
+				from[end] = m
+			}
+		}
+	}
+
+	// walk back through the best path, joining brute force characters
+	var path []Match
+	for i := n; i > 0; {
+		m := *from[i]
+		if m.Pattern == "bruteforce" && len(path) > 0 && path[0].Pattern == "bruteforce" {
+			path[0].Token = m.Token + path[0].Token
+			path[0].Start = m.Start
+			path[0].Guesses *= m.Guesses
+		} else {
+			path = append([]Match{m}, path...)
+		}
+		i = m.Start
+	}
+	// the number of ways the matches could be ordered
+	orderings := 0.0
+	for k := 2; k <= len(path); k++ {
+		orderings += math.Log10(float64(k))
+	}
+	return Strength{Matches: path, Guesses: math.Pow(10, best[n]+orderings)}
+}
+
+// cardinality returns the size of the character class c belongs to, as
+// the number of guesses needed to brute force that character.
+func cardinality(c rune) int {
+	switch {
+	case unicode.IsDigit(c):
+		return 10
+	case unicode.IsLower(c), unicode.IsUpper(c):
+		return 26
+	}
+	return 33
+}
+
+// dictionaryMatches finds every known word in the password, including
+// those in mixed case or with leet substitutions.
+func dictionaryMatches(runes []rune) []Match {
+	var matches []Match
+	for i := 0; i < len(runes); i++ {
+		for j := i + 3; j <= len(runes) && j-i <= 20; j++ {
+			token := string(runes[i:j])
+			lower := strings.ToLower(token)
+			if rank, ok := dictionary[lower]; ok {
+				matches = append(matches, Match{"dictionary", token, i, j, float64(rank) * caseVariations(token), lower})
+			}
+			for _, plain := range unleet([]rune(lower)) {
+				if rank, ok := dictionary[plain]; ok {
+					guesses := float64(rank) * caseVariations(token) * leetVariations([]rune(lower), []rune(plain))
+					matches = append(matches, Match{"leet", token, i, j, guesses, plain})
+				}
+			}
+		}
+	}
+	return matches
+}
+
+// unleet returns the versions of the lower case word with each leet
+// character replaced by the letters it may stand for. Nothing is returned
+// if the word contains no leet characters.
+func unleet(word []rune) []string {
+	variants := []string{""}
+	changed := false
+	for _, c := range word {
+		options, ok := leet[c]
+		if !ok {
+			options = []rune{c}
+		} else {
+			changed = true
+		}
+		var next []string
+		for _, v := range variants {
+			for _, o := range options {
+				next = append(next, v+string(o))
+			}
+		}
+		variants = next
+		if len(variants) > 16 {
+			return nil
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return variants
+}
+
+// binomial returns n choose k.
+func binomial(n, k int) float64 {
+	if k > n {
+		return 0
+	}
+	r := 1.0
+	for i := 1; i <= k; i++ {
+		r = r * float64(n-k+i) / float64(i)
+	}
+	return r
+}
+
+// caseVariations returns how many case patterns an attacker would try to
+// find the token. All lower case, all upper case or a capital first or
+// last letter are common, so add little.
+func caseVariations(token string) float64 {
+	runes := []rune(token)
+	upper, lower := 0, 0
+	for _, c := range runes {
+		if unicode.IsUpper(c) {
+			upper++
+		} else if unicode.IsLower(c) {
+			lower++
+		}
+	}
+	switch {
+	case upper == 0:
+		return 1
+	case lower == 0, upper == 1 && (unicode.IsUpper(runes[0]) || unicode.IsUpper(runes[len(runes)-1])):
+		return 2
+	}
+	variations := 0.0
+	for i := 1; i <= upper && i <= lower; i++ {
+		variations += binomial(upper+lower, i)
+	}
+	return variations
+}
+
+// leetVariations returns how many substitution patterns an attacker would
+// try to find the leet version of the plain word.
+func leetVariations(leeted, plain []rune) float64 {
+	variations := 1.0
+	subbed := map[rune]int{}
+	unsubbed := map[rune]int{}
+	for i := range plain {
+		if leeted[i] != plain[i] {
+			subbed[plain[i]]++
+		} else {
+			unsubbed[plain[i]]++
+		}
+	}
+	for c, s := range subbed {
+		u := unsubbed[c]
+		if u == 0 {
+			variations *= 2
+			continue
+		}
+		total := 0.0
+		for i := 1; i <= s && i <= u; i++ {
+			total += binomial(s+u, i)
+		}
+		variations *= total
+	}
+	return variations
+}
+
+// keyboard holds the UK/US qwerty keyboard rows, as pairs of unshifted and
+// shifted characters, and the offset of each row from the left.
+var keyboard = []struct {
+	keys   string
+	offset float64
+}{
+	{"`~1!2@3#4$5%6^7&8*9(0)-_=+", 0},
+	{"qQwWeErRtTyYuUiIoOpP[{]}\\|", 1.5},
+	{"aAsSdDfFgGhHjJkKlL;:'\"", 1.75},
+	{"zZxXcCvVbBnNmM,<.>/?", 2.25},
+}
+
+// keyPosition returns the row and horizontal position of the key used
+// to type c, and whether shift is needed. The row is -1 if c is not on
+// the keyboard.
+func keyPosition(c rune) (row int, x float64, shifted bool) {
+	for r, kr := range keyboard {
+		for i, k := range []rune(kr.keys) {
+			if k == c {
+				return r, kr.offset + float64(i/2), i%2 == 1
+			}
+		}
+	}
+	return -1, 0, false
+}
+
+// keyDirection returns a number for the direction taken from key a to key
+// b if they are next to each other on the keyboard, or zero if not.
+func keyDirection(a, b rune) int {
+	ra, xa, _ := keyPosition(a)
+	rb, xb, _ := keyPosition(b)
+	if ra < 0 || rb < 0 {
+		return 0
+	}
+	dx := xb - xa
+	switch {
+	case ra == rb && dx == 1:
+		return 1
+	case ra == rb && dx == -1:
+		return 2
+	case rb == ra-1 && dx > -1 && dx < 0:
+		return 3
+	case rb == ra-1 && dx > 0 && dx < 1:
+		return 4
+	case rb == ra+1 && dx > -1 && dx < 0:
+		return 5
+	case rb == ra+1 && dx > 0 && dx < 1:
+		return 6
+	}
+	return 0
+}
+
+// spatialMatches finds keyboard walks of three or more adjacent keys, such
+// as 'qwerty' or 'zaq1'.
+func spatialMatches(runes []rune) []Match {
+	var matches []Match
+	const starts, degree = 94.0, 4.6
+	for i := 0; i < len(runes)-2; {
+		j, turns, direction := i+1, 0, 0
+		for ; j < len(runes); j++ {
+			d := keyDirection(runes[j-1], runes[j])
+			if d == 0 {
+				break
+			}
+			if d != direction {
+				turns++
+				direction = d
+			}
+		}
+		if j-i < 3 {
+			i++
+			continue
+		}
+		// possible walks of this length with up to this number of turns
+		guesses := 0.0
+		for length := 2; length <= j-i; length++ {
+			for t := 1; t <= turns && t <= length-1; t++ {
+				guesses += binomial(length-1, t-1) * starts * math.Pow(degree, float64(t))
+			}
+		}
+		shifted := 0
+		for _, c := range runes[i:j] {
+			if _, _, s := keyPosition(c); s {
+				shifted++
+			}
+		}
+		if shifted > 0 {
+			guesses *= caseVariationCount(shifted, j-i-shifted)
+		}
+		matches = append(matches, Match{"keyboard", string(runes[i:j]), i, j, guesses, fmt.Sprintf("%d turns", turns)})
+		i = j
+	}
+	return matches
+}
+
+// caseVariationCount returns the number of ways to choose which of the
+// characters are shifted, as used by caseVariations.
+func caseVariationCount(shifted, unshifted int) float64 {
+	if unshifted == 0 {
+		return 2
+	}
+	total := 0.0
+	for i := 1; i <= shifted && i <= unshifted; i++ {
+		total += binomial(shifted+unshifted, i)
+	}
+	return total
+}
+
+// repeatMatches finds a character or group of characters repeated, such
+// as 'aaa' or 'abcabc'. Only the longest repeat starting at each position
+// is kept, using the shortest group if there is a choice. The guesses for
+// each group repeated are looked up in memo, or estimated and added to it.
+func repeatMatches(runes []rune, memo map[string]float64) []Match {
+	var matches []Match
+	for i := 0; i < len(runes); i++ {
+		bestSize, bestCount := 0, 0
+		for size := 1; i+size*2 <= len(runes); size++ {
+			count := 1
+			for i+(count+1)*size <= len(runes) && sameRunes(runes[i+count*size:i+(count+1)*size], runes[i:i+size]) {
+				count++
+			}
+			if count >= 2 && count*size >= 3 && count*size > bestCount*bestSize {
+				bestSize, bestCount = size, count
+			}
+		}
+		if bestCount == 0 {
+			continue
+		}
+		base := string(runes[i : i+bestSize])
+		guesses, ok := memo[base]
+		if !ok {
+			guesses = estimate([]rune(base), memo).Guesses
+			memo[base] = guesses
+		}
+		end := i + bestCount*bestSize
+		matches = append(matches, Match{"repeat", string(runes[i:end]), i, end, guesses * float64(bestCount), fmt.Sprintf("'%s' x %d", base, bestCount)})
+	}
+	return matches
+}
+
+// sameRunes reports whether a and b hold the same characters.
+func sameRunes(a, b []rune) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// sequenceMatches finds runs of three or more characters that go up or
+// down by one each time, such as 'abc', '987' or 'XYZ'.
+func sequenceMatches(runes []rune) []Match {
+	var matches []Match
+	for i := 0; i < len(runes)-2; {
+		step := runes[i+1] - runes[i]
+		j := i + 1
+		if (step == 1 || step == -1) && charClass(runes[i]) == charClass(runes[i+1]) {
+			for j+1 <= len(runes)-1 && runes[j+1]-runes[j] == step && charClass(runes[j+1]) == charClass(runes[i]) {
+				j++
+			}
+		}
+		if j-i+1 < 3 {
+			i++
+			continue
+		}
+		base := 26.0
+		switch {
+		case strings.ContainsRune("aAzZ019", runes[i]):
+			base = 4
+		case unicode.IsDigit(runes[i]):
+			base = 10
+		}
+		if step < 0 {
+			base *= 2
+		}
+		length := j - i + 1
+		matches = append(matches, Match{"sequence", string(runes[i : j+1]), i, j + 1, base * float64(length), ""})
+		i = j + 1
+	}
+	return matches
+}
+
+// dateMatches finds dates made of digits, with or without separators, such
+// as '1987', '25/12/1999' or '311299'.
+func dateMatches(runes []rune) []Match {
+	var matches []Match
+	refYear := time.Now().Year()
+	yearSpace := func(year int) float64 {
+		return math.Max(math.Abs(float64(year-refYear)), 20)
+	}
+	for i := 0; i < len(runes); i++ {
+		for j := i + 4; j <= len(runes) && j-i <= 10; j++ {
+			token := string(runes[i:j])
+			if year, ok := parseYear(token); ok && len(token) == 4 {
+				matches = append(matches, Match{"date", token, i, j, yearSpace(year), "year"})
+				continue
+			}
+			if year, sep, ok := parseDate(token); ok {
+				guesses := yearSpace(year) * 365
+				if sep {
+					guesses *= 4
+				}
+				matches = append(matches, Match{"date", token, i, j, guesses, "date"})
+			}
+		}
+	}
+	return matches
+}
+
+// parseYear returns the year given as four digits from 1900 to 2099, or
+// as two digits.
+func parseYear(s string) (int, bool) {
+	if len(s) != 2 && len(s) != 4 {
+		return 0, false
+	}
+	year := 0
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		year = year*10 + int(c-'0')
+	}
+	switch {
+	case len(s) == 2 && year >= 50:
+		return 1900 + year, true
+	case len(s) == 2:
+		return 2000 + year, true
+	case year >= 1900 && year <= 2099:
+		return year, true
+	}
+	return 0, false
+}
+
+// parseDate checks if s is a day, month and year in any common order,
+// either split by a separator such as '/' or as digits alone. The year
+// is returned, along with whether a separator was used.
+func parseDate(s string) (year int, sep bool, ok bool) {
+	var parts []string
+	if fields := strings.FieldsFunc(s, func(c rune) bool { return strings.ContainsRune("/-. _", c) }); len(fields) == 3 {
+		// the separators must be the same, and the fields must be digits
+		seps := strings.Map(func(c rune) rune {
+			if c >= '0' && c <= '9' {
+				return -1
+			}
+			return c
+		}, s)
+		if len(seps) != 2 || seps[0] != seps[1] {
+			return 0, false, false
+		}
+		parts, sep = fields, true
+		if y, ok := datePartsYear(parts); ok {
+			return y, sep, true
+		}
+		return 0, false, false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return 0, false, false
+		}
+	}
+	// try every split of the digits into three parts
+	for a := 1; a <= 4 && a < len(s)-1; a++ {
+		for b := 1; b <= 4 && a+b < len(s); b++ {
+			if len(s)-a-b > 4 {
+				continue
+			}
+			if y, ok := datePartsYear([]string{s[:a], s[a : a+b], s[a+b:]}); ok {
+				return y, false, true
+			}
+		}
+	}
+	return 0, false, false
+}
+
+// datePartsYear checks if the three parts are a valid day, month and year,
+// with the year first or last, and returns the year.
+func datePartsYear(parts []string) (int, bool) {
+	number := func(s string) int {
+		n := 0
+		for _, c := range s {
+			if c < '0' || c > '9' || len(s) > 2 {
+				return -1
+			}
+			n = n*10 + int(c-'0')
+		}
+		return n
+	}
+	dayMonth := func(a, b string) bool {
+		d, m := number(a), number(b)
+		return (d >= 1 && d <= 31 && m >= 1 && m <= 12) || (m >= 1 && m <= 31 && d >= 1 && d <= 12)
+	}
+	if y, ok := parseYear(parts[2]); ok && dayMonth(parts[0], parts[1]) {
+		return y, true
+	}
+	if y, ok := parseYear(parts[0]); ok && dayMonth(parts[2], parts[1]) {
+		return y, true
+	}
+	return 0, false
+}
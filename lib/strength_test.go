@@ -0,0 +1,90 @@
+package lib
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEstimateStrengthPatterns(t *testing.T) {
+	tests := []struct {
+		password string
+		patterns []string // the pattern of each match, in order
+	}{
+		{"", nil},
+		{"password", []string{"dictionary"}},
+		{"P@ssw0rd", []string{"leet"}},
+		{"ghjkl", []string{"keyboard"}},
+		{"abcdef", []string{"sequence"}},
+		{"aaaaaa", []string{"repeat"}},
+		{"abcabcabc", []string{"repeat"}},
+		{"25/12/1999", []string{"date"}},
+	}
+	for _, tt := range tests {
+		s := EstimateStrength(tt.password)
+		var patterns []string
+		for _, m := range s.Matches {
+			patterns = append(patterns, m.Pattern)
+		}
+		if strings.Join(patterns, ",") != strings.Join(tt.patterns, ",") {
+			t.Errorf("EstimateStrength(%q) found %q, want %q", tt.password, patterns, tt.patterns)
+		}
+	}
+}
+
+func TestEstimateStrengthOrder(t *testing.T) {
+	// each password is harder to guess than the one before
+	passwords := []string{"password", "aaa", "ghjkl", "catdogpig", "tr0ub4dor", "cat-dog-pig-7"}
+	last := 0.0
+	for _, p := range passwords {
+		g := EstimateStrength(p).Guesses
+		if g <= last {
+			t.Errorf("EstimateStrength(%q) = %.3g guesses, want more than %.3g", p, g, last)
+		}
+		last = g
+	}
+}
+
+func TestEstimateStrengthLong(t *testing.T) {
+	// long repeats once took minutes, as each repeated group was estimated
+	// again for every start and size
+	tests := []string{
+		strings.Repeat("a", 200),
+		strings.Repeat("abc", 60),
+		strings.Repeat("xK9!", 50),
+	}
+	for _, p := range tests {
+		start := time.Now()
+		s := EstimateStrength(p)
+		if d := time.Since(start); d > 2*time.Second {
+			t.Errorf("EstimateStrength of %d characters took %v", len(p), d)
+		}
+		end := 0
+		for _, m := range s.Matches {
+			end = m.End
+		}
+		if end != MaxStrengthLength {
+			t.Errorf("EstimateStrength of %d characters scored %d, want %d", len(p), end, MaxStrengthLength)
+		}
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		seconds float64
+		want    string
+	}{
+		{0.5, "less than a second"},
+		{1, "1 second"},
+		{90, "2 minutes"},
+		{3 * 3600, "3 hours"},
+		{24 * 3600, "1 day"},
+		{365.25 * 24 * 3600 * 5, "5 years"},
+		{365.25 * 24 * 3600 * 500, "centuries"},
+	}
+	for _, tt := range tests {
+		if got := FormatDuration(tt.seconds); got != tt.want {
+			t.Errorf("FormatDuration(%v) = %q, want %q", tt.seconds, got, tt.want)
+		}
+	}
+}
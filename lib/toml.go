@@ -0,0 +1,146 @@
+package lib
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Table holds the keys and values read from one '[table]' section of a
+// TOML file. Values are of type string, int64, bool or []interface{}.
+type Table map[string]interface{}
+
+// ParseTOML reads the small subset of TOML used by the passgen settings
+// files: '[table]' headers, 'key = value' pairs, and values that are
+// strings, integers, booleans or single line arrays of these. Keys given
+// before the first table header are returned under the table name "".
+// The table names are also returned in the order they were found.
+func ParseTOML(r io.Reader) (map[string]Table, []string, error) {
+	tables := map[string]Table{"": {}}
+	var order []string
+	current := tables[""]
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if strings.HasPrefix(text, "[") {
+			end := strings.Index(text, "]")
+			if end < 0 || strings.TrimSpace(stripComment(text[end+1:])) != "" {
+				return nil, nil, fmt.Errorf("line %d: badly formed table header: %s", line, text)
+			}
+			name := strings.Trim(strings.TrimSpace(text[1:end]), `"`)
+			if _, ok := tables[name]; ok {
+				return nil, nil, fmt.Errorf("line %d: table [%s] is defined twice", line, name)
+			}
+			current = Table{}
+			tables[name] = current
+			order = append(order, name)
+			continue
+		}
+		eq := strings.Index(text, "=")
+		if eq < 0 {
+			return nil, nil, fmt.Errorf("line %d: expected 'key = value': %s", line, text)
+		}
+		key := strings.Trim(strings.TrimSpace(text[:eq]), `"`)
+		value, rest, err := parseValue(strings.TrimSpace(text[eq+1:]))
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: %s: %s", line, key, err)
+		}
+		if strings.TrimSpace(stripComment(rest)) != "" {
+			return nil, nil, fmt.Errorf("line %d: %s: unexpected text after value: %s", line, key, rest)
+		}
+		current[key] = value
+	}
+	return tables, order, scanner.Err()
+}
+
+// stripComment removes a trailing '# comment' from the text given.
+func stripComment(s string) string {
+	if i := strings.Index(s, "#"); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// parseValue reads one value from the start of s, and returns it along
+// with any text left over after the value.
+func parseValue(s string) (interface{}, string, error) {
+	switch {
+	case s == "":
+		return nil, "", fmt.Errorf("missing value")
+	case s[0] == '"':
+		// basic string - supports the common back slash escapes
+		var b strings.Builder
+		for i := 1; i < len(s); i++ {
+			switch s[i] {
+			case '"':
+				return b.String(), s[i+1:], nil
+			case '\\':
+				i++
+				if i == len(s) {
+					break
+				}
+				switch s[i] {
+				case 'n':
+					b.WriteByte('\n')
+				case 't':
+					b.WriteByte('\t')
+				case '"', '\\':
+					b.WriteByte(s[i])
+				default:
+					return nil, "", fmt.Errorf("unsupported escape '\\%c'", s[i])
+				}
+			default:
+				b.WriteByte(s[i])
+			}
+		}
+		return nil, "", fmt.Errorf("unterminated string")
+	case s[0] == '\'':
+		// literal string - no escapes
+		end := strings.Index(s[1:], "'")
+		if end < 0 {
+			return nil, "", fmt.Errorf("unterminated string")
+		}
+		return s[1 : end+1], s[end+2:], nil
+	case s[0] == '[':
+		var array []interface{}
+		rest := strings.TrimSpace(s[1:])
+		for {
+			if strings.HasPrefix(rest, "]") {
+				return array, rest[1:], nil
+			}
+			value, after, err := parseValue(rest)
+			if err != nil {
+				return nil, "", err
+			}
+			array = append(array, value)
+			rest = strings.TrimSpace(after)
+			if strings.HasPrefix(rest, ",") {
+				rest = strings.TrimSpace(rest[1:])
+			} else if !strings.HasPrefix(rest, "]") {
+				return nil, "", fmt.Errorf("expected ',' or ']' in array")
+			}
+		}
+	}
+	// bare value - a boolean or an integer
+	end := strings.IndexAny(s, " \t,]#")
+	if end < 0 {
+		end = len(s)
+	}
+	word, rest := s[:end], s[end:]
+	switch word {
+	case "true":
+		return true, rest, nil
+	case "false":
+		return false, rest, nil
+	}
+	n, err := strconv.ParseInt(strings.Replace(word, "_", "", -1), 10, 64)
+	if err != nil {
+		return nil, "", fmt.Errorf("unsupported value %q", word)
+	}
+	return n, rest, nil
+}
@@ -0,0 +1,80 @@
+package lib
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTOML(t *testing.T) {
+	input := `# a settings file
+words = 4
+name = "cat \"dog\"\tpig\\"   # a comment
+path = 'C:\passgen\#1'
+mixed = true
+quiet = false
+big = 1_000
+negative = -3
+
+[first]
+list = [ "a", 'b', 3, true ]
+empty = []
+
+["second"]
+"quoted key" = "#not a comment"
+`
+	tables, order, err := ParseTOML(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseTOML returned %v", err)
+	}
+	want := map[string]Table{
+		"": {
+			"words":    int64(4),
+			"name":     "cat \"dog\"\tpig\\",
+			"path":     `C:\passgen\#1`,
+			"mixed":    true,
+			"quiet":    false,
+			"big":      int64(1000),
+			"negative": int64(-3),
+		},
+		"first": {
+			"list":  []interface{}{"a", "b", int64(3), true},
+			"empty": []interface{}(nil),
+		},
+		"second": {
+			"quoted key": "#not a comment",
+		},
+	}
+	if !reflect.DeepEqual(tables, want) {
+		t.Errorf("ParseTOML gave %#v, want %#v", tables, want)
+	}
+	if !reflect.DeepEqual(order, []string{"first", "second"}) {
+		t.Errorf("table order = %q, want [first second]", order)
+	}
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"[open", "line 1: badly formed table header: [open"},
+		{"[a] extra", "line 1: badly formed table header: [a] extra"},
+		{"[a]\n[a]", "line 2: table [a] is defined twice"},
+		{"just words", "line 1: expected 'key = value': just words"},
+		{"key =", "line 1: key: missing value"},
+		{`key = "open`, "line 1: key: unterminated string"},
+		{"key = 'open", "line 1: key: unterminated string"},
+		{`key = "\q"`, `line 1: key: unsupported escape '\q'`},
+		{"key = [1 2]", "line 1: key: expected ',' or ']' in array"},
+		{"key = 1.5", `line 1: key: unsupported value "1.5"`},
+		{"key = yes", `line 1: key: unsupported value "yes"`},
+		{`key = "a" "b"`, `line 1: key: unexpected text after value:  "b"`},
+	}
+	for _, tt := range tests {
+		_, _, err := ParseTOML(strings.NewReader(tt.input))
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("ParseTOML(%q) returned %v, want %s", tt.input, err, tt.want)
+		}
+	}
+}
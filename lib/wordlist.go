@@ -0,0 +1,22 @@
+package lib
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// WordlistName is the name of the three letter word list held in Passmap.
+const WordlistName = "ABSP three letter words"
+
+// WordlistFingerprint returns the SHA-256 hash of the words in Passmap, in
+// the order of their keys with each word followed by a new line, as a hex
+// string. Any change to the words, or to the keys used to pick them,
+// changes the fingerprint - so it records exactly which vocabulary made a
+// password.
+func WordlistFingerprint() string {
+	h := sha256.New()
+	for i := 1; i <= len(Passmap); i++ {
+		h.Write([]byte(Passmap[i] + "\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -10,13 +10,10 @@ package main
 import (
 	"flag"
 	"fmt"
-	"math/rand"
 	"os"
 	"path/filepath"
-	"runtime"
 	"strconv"
 	"strings"
-	"time"
 	"unicode"
 
 	pg "github.com/wiremoons/passgen/lib"
@@ -36,114 +33,350 @@ var helpMe bool
 var quiet bool
 var remove bool
 var version bool
+var pwquality = optionalPath{def: pg.PWQualityPath}
+var policyName string
+var policyFile string
+var breachPath string
+var unique bool
+var phonetic bool
+
+// seen holds the passwords already output when '--unique' is used, so no
+// password is repeated within a batch
+var seen = map[string]bool{}
+
+// breachCorpus is opened when '--breach' is used, so generated passwords
+// found in the corpus can be rejected
+var breachCorpus *pg.BreachCorpus
+
+// the largest values accepted for '-w' and '-s', to stop runaway memory use
+const maxWords = 1000
+const maxSuggestions = 10000
+
+// maxAttempts is the number of passwords generated while looking for one
+// that meets a password policy, before giving up
+const maxAttempts = 10000
+
+// optionalPath is a flag value that may be given with or without a path:
+// '--pwquality' alone uses the default path, while '--pwquality=PATH'
+// uses the path given.
+type optionalPath struct {
+	set  bool
+	path string
+	def  string
+}
+
+func (o *optionalPath) String() string { return o.path }
+
+func (o *optionalPath) IsBoolFlag() bool { return true }
+
+func (o *optionalPath) Set(value string) error {
+	switch value {
+	case "true":
+		o.set, o.path = true, o.def
+	case "false":
+		o.set, o.path = false, ""
+	default:
+		o.set, o.path = true, value
+	}
+	return nil
+}
 
 // init function always runs before main() so used here to
-// set-up the required command line flag variables
+// set-up the application name used in the output and help text
 func init() {
-	// IntVar; StringVar; BoolVar options for flag
-	// format required: variable, cmd line flag, initial value, description.
-	flag.BoolVar(&passcase, "c", false, "\tUSE: '-c=true' provide mixed case passwords. Note: useful with -q only [DEFAULT: lowercase]")
-	flag.BoolVar(&helpMe, "h", false, "\tUSE: '-h' display more detailed help about this program")
-	flag.BoolVar(&quiet, "q", false, "\tUSE: '-q=true' to obtain just ONE password - no other screen output [DEFAULT: additional info output]")
-	flag.BoolVar(&remove, "r", false, "\tUSE: '-r=true' remove password spaces. Note: useful with -q only [DEFAULT: with spaces]")
-	flag.IntVar(&numsuggestions, "s", 3, "\tUSE: '-s #' where # is the number of password suggestions offered [DEFAULT: 3]")
-	flag.BoolVar(&version, "v", false, "\tUSE: '-v=true.' display the application version [DEFAULT: false]")
-	flag.IntVar(&numwords, "w", 3, "\tUSE: '-w #' where # is the number of three letter words to use [DEFAULT: 3]")
 	appname = filepath.Base(os.Args[0])
 }
 
 func main() {
-	// get the command line args passed to the program
-	flag.Parse()
+	// the first argument selects the sub command to run - if none is
+	// given, or the first argument is a flag such as '-q', then the
+	// passwords are generated as in earlier versions of the application
+	name, args := "generate", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	cmd := findCommand(name)
+	if cmd == nil {
+		os.Exit(fail(usageErrorf("unknown command '%s' - run '%s help' to see the commands available", name, appname)))
+	}
+	os.Exit(cmd.execute(args))
+}
 
-	// was the command line flag '-h' used?
+// runGenerate handles the 'generate' sub command, which is also run when
+// no sub command is given. The exit code to use is returned.
+func runGenerate(fs *flag.FlagSet) int {
+	// were the old '-h' or '-v' flags used? These are kept so existing
+	// scripts still work, and are the same as the 'help' and 'version'
+	// sub commands
 	if helpMe {
-		// call function to display information about the application
-		pg.PrintHelp()
-		// call to display the standard command line usage info
-		flag.Usage()
-		// let user know we ran as expected
-		fmt.Printf("\n\nAll is well.\n\n")
-		// exit the application
-		os.Exit(0)
+		return runHelp(fs)
 	}
-
-	// was the command line flag '-v' used?
 	if version {
-		// print app name called and version information
-		fmt.Printf("\n Running %s version %s\n", appname, appversion)
-		fmt.Printf(" Built with Go Complier '%s' on Golang version '%s'\n", runtime.Compiler, runtime.Version())
-		fmt.Printf(" - Author's web site: http://www.wiremoons.com/\n")
-		fmt.Printf(" - Source code for %s: https://github.com/wiremoons/passgen/\n", appname)
-		fmt.Printf("\nAll is well\n")
-		// exit the application
-		os.Exit(0)
+		return runVersion(fs)
 	}
 
 	// check how many three letter words the user wants to include in
-	// their new password?
-	// if given a zero or negative value then reset to '3' the default
-	if numwords <= 0 {
-		numwords = 3
+	// their new password, and how many password suggestions they want,
+	// are both within range
+	if err := checkRange("w", numwords, 1, maxWords); err != nil {
+		return fail(err)
+	}
+	if err := checkRange("s", numsuggestions, 1, maxSuggestions); err != nil {
+		return fail(err)
+	}
+	if err := checkCopy(); err != nil {
+		return fail(err)
+	}
+	if err := checkOut(); err != nil {
+		return fail(err)
+	}
+	if err := checkQR(); err != nil {
+		return fail(err)
+	}
+	if err := checkColour(); err != nil {
+		return fail(err)
+	}
+	if err := checkHash(); err != nil {
+		return fail(err)
+	}
+
+	// was '--breach' used? If so open the breach corpus so passwords found
+	// in it can be rejected
+	if breachPath != "" {
+		var err error
+		if breachCorpus, err = pg.OpenBreachCorpus(breachPath); err != nil {
+			return fail(ioErrorf("unable to open the breach corpus: %s", err))
+		}
+		defer breachCorpus.Close()
+	}
+
+	// was '--policy' or '--pwquality' used? If so generate passwords that
+	// meet the rules of the chosen password policy
+	policy, usePolicy, err := selectPolicy()
+	if err != nil {
+		return fail(err)
+	}
+	if interactive && usePolicy {
+		return fail(usageErrorf("'-i' can not be used with a password policy"))
+	}
+
+	// was '--clear' used? If so show the passwords on the alternate screen,
+	// and clear them after a time or a key press
+	if clearAfter > 0 {
+		if interactive {
+			return fail(usageErrorf("'--clear' can not be used with '-i', which always clears its screen"))
+		}
+		if err := checkRange("clear", clearAfter, 1, maxClear); err != nil {
+			return fail(err)
+		}
+		return showThenClear(func() int { return generate(policy, usePolicy) })
+	}
+	return generate(policy, usePolicy)
+}
+
+// generate outputs the passwords in the form chosen on the command line:
+// to files, meeting a password policy, in the interactive screen, in quiet
+// mode or in the default table. The exit code to use is returned.
+func generate(policy pg.Policy, usePolicy bool) int {
+	// '--out' writes the passwords to files instead of stdout
+	if outPath != "" {
+		next, err := passwordSource(policy, usePolicy)
+		if err != nil {
+			return fail(err)
+		}
+		return runOut(next)
+	}
+
+	// check where passwords written to stdout will end up - the other modes
+	// need a terminal, or do not write passwords to stdout
+	if !interactive && (!copyPassword || qrShow) {
+		if err := guardStdout(); err != nil {
+			return fail(err)
+		}
 	}
-	// check how many password suggestions the user wants to include?
-	// if given a zero or negative value then reset to '3' the default
-	if numsuggestions <= 0 {
-		numsuggestions = 3
+
+	if usePolicy {
+		return runPolicy(policy)
 	}
 
-	// create a seed from current time
-	rand.Seed(time.Now().UTC().UnixNano())
+	// interactive mode - choose and refine a password on screen
+	if interactive {
+		return runInteractive()
+	}
 
-	// quiet mode - so just output ONE password (ie -s 1) at whatever word
-	// length for -w and nothing else. Also check for removal of spaces
-	// and mixed case preference
+	// quiet mode - so just output ONE password (unless -s is also given)
+	// at whatever word length for -w and nothing else, one per line. The
+	// form of the password is set by the removal of spaces and mixed case
+	// preferences
 	if quiet {
-		// variable to hold quite password 'qpassword'
-		var qpassword string
-		// remove spaces in password if true on command line with -r
-		if remove {
-			qpassword = strings.Replace(getPassword(numwords), " ", "", -1)
-		} else {
-			// just get password with spaces
-			qpassword = getPassword(numwords)
+		var passwords []string
+		for i := quietCount(); i > 0; i-- {
+			// variable to hold quite password 'qpassword'
+			qpassword, err := nextPassword(func() []string {
+				return []string{formPassword(getPassword(numwords))}
+			})
+			if err != nil {
+				return fail(err)
+			}
+			passwords = append(passwords, qpassword[0])
+		}
+		return outputQuiet(passwords)
+	}
+
+	// without '-c' or '-r' each suggestion is shown in all three forms:
+	// with spaces, without spaces, and mixed case without spaces - with a
+	// random number as well. Otherwise just the form chosen is shown.
+	allForms := !passcase && !remove
+
+	// get password suggestion(s) based on number requested (numsuggestions),
+	// and include specified number  of three letter words requested (numword).
+	// They are all made before any are output, so nothing is output if
+	// '--unique' runs out of new passwords.
+	var suggestions [][]string
+	for i := numsuggestions; i > 0; i-- {
+		if !allForms {
+			password, err := nextPassword(func() []string {
+				return []string{formPassword(getPassword(numwords))}
+			})
+			if err != nil {
+				return fail(err)
+			}
+			suggestions = append(suggestions, password)
+			continue
 		}
-		// check if mixed case password requested with -c
-		if passcase {
-			qpassword = mixedPassword(qpassword)
+		// generate again if any version is found in the breach corpus
+		forms, err := nextPassword(func() []string {
+			// defaultpass: passwords with spaces included between words
+			defaultpass := getPassword(numwords)
+			// nospacepass: passwords with NO spaces included between words
+			nospacepass := strings.Replace(defaultpass, " ", "", -1)
+			// get a mixed case password
+			mixedcasepass := mixedPassword(nospacepass)
+			return []string{defaultpass, nospacepass, mixedcasepass}
+		})
+		if err != nil {
+			return fail(err)
 		}
-		fmt.Printf("%s\n", qpassword)
-		// done - so exit application
-		os.Exit(0)
+		suggestions = append(suggestions, forms)
 	}
 
-	// default output is to include mixed case passwords and provide a
-	// random number as well
-	passcase = true
 	// OK - so run as normal and display output
 	fmt.Printf("\n\t\t\tTHREE WORD - PASSWORD GENERATOR\n\t\t\t¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯\n")
 	fmt.Printf("» Number of three letter words available in the pool is: %d\n", (len(pg.Passmap)))
 	fmt.Printf("» Number of three letter words to include in the suggested password is: %d\n", numwords)
 	fmt.Printf("\t» Password character length will therefore be: %d\n", (numwords * 3))
-	fmt.Printf("» Mixed case passwords to be provided: %s\n", strconv.FormatBool(passcase))
+	fmt.Printf("» Mixed case passwords to be provided: %s\n", strconv.FormatBool(allForms || passcase))
 	fmt.Printf("» Offering %d suggested passwords for your consideration:\n\n", numsuggestions)
 
-	// get password suggestion(s) based on number requested (numsuggestions),
-	// and include specified number  of three letter words requested (numword)
-	for ; numsuggestions > 0; numsuggestions-- {
-		// defaultpass: passwords with spaces included between words
-		defaultpass := getPassword(numwords)
-		// nospacepass: passwords with NO spaces included between words
-		nospacepass := strings.Replace(defaultpass, " ", "", -1)
-		// get a mixed case password
-		mixedcasepass := mixedPassword(nospacepass)
-		//fmt.Printf("\t%s\n", getPassword(numwords))
-		fmt.Printf("\t%s    %s    %s    %d\n", defaultpass, nospacepass, mixedcasepass, rand.Intn(100))
+	for _, forms := range suggestions {
+		if !allForms {
+			fmt.Printf("\t%s    %d\n", paint(forms[0]), rng.Intn(100))
+			printPhonetic(forms[0])
+			continue
+		}
+		fmt.Printf("\t%s    %s    %s    %d\n", paint(forms[0]), paint(forms[1]), paint(forms[2]), rng.Intn(100))
+		// only the mixed case form is spelled out, as the others are the
+		// same words in lower case
+		printPhonetic(forms[2])
 	}
 
 	fmt.Printf("\nTo change the password suggestion output shown above, use the command line options.\n")
-	fmt.Printf("Run the program as follows for more help:  %s -h\n", appname)
+	fmt.Printf("Run the program as follows for more help:  %s help\n", appname)
 	fmt.Printf("\nAll is well\n")
+	return exitOK
+}
+
+// printPhonetic outputs the password spelled out with the NATO phonetic
+// alphabet, under a suggestion in the table of suggestions, when
+// '--phonetic' is used.
+func printPhonetic(password string) {
+	if phonetic {
+		fmt.Printf("\t  » %s\n", pg.Phonetic(password))
+	}
+}
+
+// outputQuiet outputs the passwords for quiet mode, one per line with
+// nothing else, or copies the password to the clipboard with '--copy'. The
+// exit code to use is returned.
+func outputQuiet(passwords []string) int {
+	for _, password := range passwords {
+		if copyPassword {
+			if err := outputQR(password); err != nil {
+				return fail(err)
+			}
+			return copyToClipboard(password)
+		}
+		line, err := withHashes(paint(password), password)
+		if err != nil {
+			return fail(err)
+		}
+		fmt.Printf("%s\n", line)
+		if phonetic {
+			fmt.Printf("%s\n", pg.Phonetic(password))
+		}
+		if err := outputQR(password); err != nil {
+			return fail(err)
+		}
+	}
+	return exitOK
+}
+
+// quietCount returns the number of passwords to output in quiet mode:
+// just ONE, as in earlier versions, unless '-s' is given on the command
+// line as well. A 'suggestions' value from the configuration file or
+// environment is a preference for the list of suggestions, so it is not
+// used here - 'PW=$(passgen -q)' must always capture one password.
+func quietCount() int {
+	if settingSources["s"] == "command line" {
+		return numsuggestions
+	}
+	return 1
+}
+
+// nextPassword calls 'generate' until it returns a password that is not
+// found in the breach corpus and, with '--unique', has not already been
+// output. The password may be returned in several forms, and the first is
+// used to spot repeats. An error is returned after 'maxAttempts' tries, or
+// if the breach corpus can not be searched.
+func nextPassword(generate func() []string) ([]string, error) {
+	for i := 0; i < maxAttempts; i++ {
+		forms := generate()
+		found, err := breached(forms...)
+		if err != nil {
+			return nil, err
+		}
+		if !found && !repeated(forms[0]) {
+			return forms, nil
+		}
+	}
+	return nil, policyErrorf("no new password found after %d attempts - use more words with '-w', or ask for fewer passwords with '-s'", maxAttempts)
+}
+
+// repeated returns true if '--unique' was used and the password has already
+// been output. Otherwise the password is recorded as output.
+func repeated(password string) bool {
+	if !unique {
+		return false
+	}
+	if seen[password] {
+		return true
+	}
+	seen[password] = true
+	return false
+}
+
+// formPassword returns the password in the form chosen on the command
+// line: without spaces if '-r' was used, and mixed case if '-c' was used.
+func formPassword(password string) string {
+	// remove spaces in password if true on command line with -r
+	if remove {
+		password = strings.Replace(password, " ", "", -1)
+	}
+	// check if mixed case password requested with -c
+	if passcase {
+		password = mixedPassword(password)
+	}
+	return password
 }
 
 // getPassword is used to return a suggested password
@@ -155,7 +388,8 @@ func getPassword(numwords int) string {
 	var passSuggestion string
 	// get three letter word associated with random number:
 	for ; numwords > 0; numwords-- {
-		passSuggestion = passSuggestion + " " + (pg.Passmap[rand.Intn(len(pg.Passmap))])
+		// Passmap keys start at one - so add one to the random number
+		passSuggestion = passSuggestion + " " + (pg.Passmap[rng.Intn(len(pg.Passmap))+1])
 	}
 	// remove leading space from password string
 	passSuggestion = strings.TrimLeft(passSuggestion, " ")
@@ -171,7 +405,7 @@ func mixedPassword(lcpassword string) string {
 	// for each letter in the password string - get a random number
 	// if random number is even make letter uppercase
 	for _, c := range lcpassword {
-		dice := rand.Intn(100)
+		dice := rng.Intn(100)
 		//fmt.Printf("random number is: %d\n", dice)
 		// if number is even
 		if dice%2 == 0 {
@@ -183,3 +417,180 @@ func mixedPassword(lcpassword string) string {
 	// done - return password suggestion
 	return mcpassword
 }
+
+// selectPolicy returns the password policy chosen with either '--policy'
+// or '--pwquality'. The boolean returned is false if neither was used.
+func selectPolicy() (pg.Policy, bool, error) {
+	// only one source of password rules can be used at a time
+	if pwquality.set && policyName != "" {
+		return pg.Policy{}, false, usageErrorf("use only one of '--pwquality' or '--policy'")
+	}
+	if policyName != "" {
+		policy, err := loadPolicy(policyName)
+		return policy, true, err
+	}
+	if pwquality.set {
+		policy, err := pg.ReadPWQuality(pwquality.path)
+		if err != nil {
+			return policy, true, ioErrorf("unable to read pwquality settings: %s", err)
+		}
+		return policy, true, nil
+	}
+	return pg.Policy{}, false, nil
+}
+
+// loadPolicy returns the named policy from the policy catalogue, which
+// includes any policies in the file given with '--policy-file'.
+func loadPolicy(name string) (pg.Policy, error) {
+	var files []string
+	if policyFile != "" {
+		files = append(files, policyFile)
+	}
+	catalogue, err := pg.LoadCatalogue(files...)
+	if err != nil {
+		return pg.Policy{}, ioErrorf("unable to load the policy catalogue: %s", err)
+	}
+	policy, err := catalogue.Get(name)
+	if err != nil {
+		return policy, usageErrorf("%s", err)
+	}
+	return policy, nil
+}
+
+// planPolicy returns the plan used to generate passwords that meet the
+// policy, with mixed case letters if '-c' was used. Passwords from a plan
+// never include spaces, so '-r' has nothing to change.
+func planPolicy(policy pg.Policy) (pg.Plan, error) {
+	plan, err := policy.Plan(numwords)
+	if err != nil {
+		return plan, policyErrorf("unable to meet the %s policy: %s", policy.Name, err)
+	}
+	if passcase && !plan.Mixed {
+		plan.Mixed = true
+		plan.Reasons = append(plan.Reasons, "-c: using mixed case letters")
+	}
+	return plan, nil
+}
+
+// breached returns true if any of the passwords given are found in the
+// breach corpus opened with '--breach'. An error is returned if the corpus
+// can not be searched.
+func breached(passwords ...string) (bool, error) {
+	if breachCorpus == nil {
+		return false, nil
+	}
+	for _, password := range passwords {
+		count, err := breachCorpus.Count(password)
+		if err != nil {
+			return false, ioErrorf("unable to search the breach corpus: %s", err)
+		}
+		if count > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// runPolicy outputs password suggestions that meet the rules in the policy
+// given. In quiet mode just ONE password is output, otherwise the rules and
+// the choices made to meet them are explained before the suggestions. The
+// exit code to use is returned.
+func runPolicy(policy pg.Policy) int {
+	plan, err := planPolicy(policy)
+	if err != nil {
+		return fail(err)
+	}
+
+	// every password is made before any is output, so nothing is output
+	// if '--unique' runs out of new passwords
+	var passwords []string
+	count := numsuggestions
+	if quiet {
+		count = quietCount()
+	}
+	for i := 0; i < count; i++ {
+		password, err := policyPassword(policy, plan)
+		if err != nil {
+			return fail(err)
+		}
+		passwords = append(passwords, password)
+	}
+	if quiet {
+		return outputQuiet(passwords)
+	}
+
+	fmt.Printf("\n\t\t\tTHREE WORD - PASSWORD GENERATOR\n\t\t\t¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯¯\n")
+	fmt.Printf("» Password policy: %s - %s\n", policy.Name, policy.Description)
+	for _, note := range policy.Notes {
+		fmt.Printf("\t» %s\n", note)
+	}
+	fmt.Printf("» Choices made to meet the policy rules:\n")
+	if len(plan.Reasons) == 0 {
+		fmt.Printf("\t» no changes needed to the default passwords\n")
+	}
+	for _, reason := range plan.Reasons {
+		fmt.Printf("\t» %s\n", reason)
+	}
+	fmt.Printf("» Password character length will therefore be: %d\n", plan.Length())
+	fmt.Printf("» Offering %d suggested passwords for your consideration:\n\n", numsuggestions)
+	for _, password := range passwords {
+		fmt.Printf("\t%s\n", paint(password))
+		printPhonetic(password)
+	}
+	fmt.Printf("\nAll is well\n")
+	return exitOK
+}
+
+// policyPassword returns a password generated from the plan that meets
+// every rule in the policy. Passwords are generated until one passes, and
+// an error is returned if none is found after 'maxAttempts' tries, or if
+// the breach corpus can not be searched.
+func policyPassword(policy pg.Policy, plan pg.Plan) (string, error) {
+	for i := 0; i < maxAttempts; i++ {
+		password := planPassword(plan)
+		if len(policy.Check(password)) > 0 {
+			continue
+		}
+		found, err := breached(password)
+		if err != nil {
+			return "", err
+		}
+		if !found && !repeated(password) {
+			return password, nil
+		}
+	}
+	return "", policyErrorf("no password meeting the %s policy found after %d attempts", policy.Name, maxAttempts)
+}
+
+// planPassword returns a password generated as described by the plan. The
+// digits and symbols are placed between the words if requested, otherwise
+// they are added to the end of the password.
+func planPassword(plan pg.Plan) string {
+	words := strings.Fields(getPassword(plan.Words))
+	if plan.Mixed {
+		for i := range words {
+			words[i] = mixedPassword(words[i])
+		}
+	}
+	// collect the digits and symbols, then shuffle them together
+	var extras []string
+	for i := 0; i < plan.Digits; i++ {
+		extras = append(extras, strconv.Itoa(rng.Intn(10)))
+	}
+	symbols := []rune(plan.SymbolSet)
+	for i := 0; i < plan.Symbols; i++ {
+		extras = append(extras, string(symbols[rng.Intn(len(symbols))]))
+	}
+	rng.Shuffle(len(extras), func(i, j int) { extras[i], extras[j] = extras[j], extras[i] })
+
+	var password strings.Builder
+	for i, word := range words {
+		password.WriteString(word)
+		if plan.Separate && i < len(words)-1 && len(extras) > 0 {
+			password.WriteString(extras[0])
+			extras = extras[1:]
+		}
+	}
+	password.WriteString(strings.Join(extras, ""))
+	return password.String()
+}
@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+func TestQuietCount(t *testing.T) {
+	defer func(sources map[string]string, n int) { settingSources, numsuggestions = sources, n }(settingSources, numsuggestions)
+	numsuggestions = 5
+	tests := []struct {
+		source string // where '-s 5' came from, if anywhere
+		want   int
+	}{
+		{"", 1},
+		{"command line", 5},
+		{"config file /home/user/.config/passgen/config.toml", 1},
+		{"environment PASSGEN_SUGGESTIONS", 1},
+	}
+	for _, tt := range tests {
+		settingSources = map[string]string{}
+		if tt.source != "" {
+			settingSources["s"] = tt.source
+		}
+		if got := quietCount(); got != tt.want {
+			t.Errorf("quietCount() with -s 5 from %q = %d, want %d", tt.source, got, tt.want)
+		}
+	}
+}
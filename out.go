@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	pg "github.com/wiremoons/passgen/lib"
+)
+
+// outPath is set by '--out' to write the passwords to a file, or to a
+// directory of files named with '--names', instead of stdout. Existing
+// files are only replaced with '--force'.
+var outPath string
+var outNames string
+var outForce bool
+
+// secretName matches the names allowed for files in directory mode, which
+// suit both Docker secrets and systemd credentials
+var secretName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// checkOut returns an error if '--out' can not be used with the other
+// options given.
+func checkOut() error {
+	if outPath == "" {
+		if outNames != "" {
+			return usageErrorf("'--names' is used with '--out'")
+		}
+		if outForce && qrFile == "" {
+			return usageErrorf("'--force' is used with '--out' or '--qr-file'")
+		}
+		return nil
+	}
+	if interactive || copyPassword || clearAfter > 0 {
+		return usageErrorf("'--out' can not be used with '-i', '--copy' or '--clear'")
+	}
+	// a default from the configuration file or environment is ignored, as
+	// the files hold just the passwords
+	if phonetic && settingSources["phonetic"] == "command line" {
+		return usageErrorf("'--phonetic' can not be used with '--out', as the files hold just the passwords")
+	}
+	if outNames == "" {
+		if info, err := os.Stat(outPath); err == nil && info.IsDir() {
+			return usageErrorf("'%s' is a directory - use '--names' to write one file per secret in it", outPath)
+		}
+		return nil
+	}
+	if settingSources["s"] == "command line" {
+		return usageErrorf("'-s' is not used with '--names' - one password is made for each name")
+	}
+	for _, name := range secretNames() {
+		if !secretName.MatchString(name) {
+			return usageErrorf("'%s' can not be used as a secret name - use letters, digits, '_', '.' and '-'", name)
+		}
+	}
+	return nil
+}
+
+// secretNames returns the names given with '--names'.
+func secretNames() []string {
+	var names []string
+	for _, name := range strings.Split(outNames, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// passwordSource returns a function that generates one password each time
+// it is called, meeting the policy if one is used, or in the form chosen
+// with '-r' and '-c' otherwise.
+func passwordSource(policy pg.Policy, usePolicy bool) (func() (string, error), error) {
+	if usePolicy {
+		plan, err := planPolicy(policy)
+		if err != nil {
+			return nil, err
+		}
+		return func() (string, error) { return policyPassword(policy, plan) }, nil
+	}
+	return func() (string, error) {
+		forms, err := nextPassword(func() []string {
+			return []string{formPassword(getPassword(numwords))}
+		})
+		if err != nil {
+			return "", err
+		}
+		return forms[0], nil
+	}, nil
+}
+
+// runOut writes the passwords made by 'next' to the file given with
+// '--out', one per line - or with '--names', to one file per name in the
+// directory given, in the layout used for Docker secrets and systemd
+// credentials: each file holds just the password, without a new line. The
+// exit code to use is returned.
+func runOut(next func() (string, error)) int {
+	if outNames == "" {
+		var data strings.Builder
+		count := quietCount()
+		for i := 0; i < count; i++ {
+			password, err := next()
+			if err != nil {
+				return fail(err)
+			}
+			line, err := withHashes(password, password)
+			if err != nil {
+				return fail(err)
+			}
+			data.WriteString(line + "\n")
+		}
+		if err := writeSecretFile(outPath, []byte(data.String()), outForce); err != nil {
+			return fail(err)
+		}
+		fmt.Fprintf(os.Stderr, "» Wrote %d password(s) to '%s' with mode 0600\n", count, outPath)
+		return exitOK
+	}
+
+	names := secretNames()
+	if err := os.MkdirAll(outPath, 0700); err != nil {
+		return fail(ioErrorf("unable to create the secrets directory: %s", err))
+	}
+	// check every file first, so no secrets are written if any exist
+	if !outForce {
+		for _, name := range names {
+			if _, err := os.Lstat(filepath.Join(outPath, name)); err == nil {
+				return fail(ioErrorf("'%s' already exists - use '--force' to replace it", filepath.Join(outPath, name)))
+			}
+		}
+	}
+	// and make every password before any is written
+	passwords := make([]string, len(names))
+	for i := range names {
+		password, err := next()
+		if err != nil {
+			return fail(err)
+		}
+		passwords[i] = password
+	}
+	for i, name := range names {
+		if err := writeSecretFile(filepath.Join(outPath, name), []byte(passwords[i]), outForce); err != nil {
+			return fail(err)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "» Wrote %d secret(s) to '%s' with mode 0600: %s\n", len(names), outPath, strings.Join(names, ", "))
+	return exitOK
+}
+
+// writeSecretFile writes the data to a new file at 'path' that only its
+// owner can read. The data is written to a temporary file in the same
+// directory first, then moved into place, so the file is never seen half
+// written or with looser permissions. An existing file is only replaced if
+// 'force' is true.
+func writeSecretFile(path string, data []byte, force bool) error {
+	if !force {
+		if _, err := os.Lstat(path); err == nil {
+			return ioErrorf("'%s' already exists - use '--force' to replace it", path)
+		}
+	}
+	// temporary files are created with mode 0600
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return ioErrorf("unable to create the secret file: %s", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0600); err != nil && !os.IsPermission(err) {
+		tmp.Close()
+		return ioErrorf("unable to set the secret file permissions: %s", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return ioErrorf("unable to write the secret file: %s", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return ioErrorf("unable to write the secret file: %s", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return ioErrorf("unable to write the secret file: %s", err)
+	}
+	if force {
+		if err := os.Rename(tmp.Name(), path); err != nil {
+			return ioErrorf("unable to move the secret file into place: %s", err)
+		}
+		return nil
+	}
+	// a hard link fails if the file now exists, so nothing is replaced even
+	// if the file was created since it was checked for above
+	if err := os.Link(tmp.Name(), path); err != nil {
+		if os.IsExist(err) {
+			return ioErrorf("'%s' already exists - use '--force' to replace it", path)
+		}
+		// some file systems do not support hard links
+		if err := os.Rename(tmp.Name(), path); err != nil {
+			return ioErrorf("unable to move the secret file into place: %s", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	pg "github.com/wiremoons/passgen/lib"
+)
+
+// practiceRounds and practiceGap set the number of times the password is
+// asked for, and the wait in seconds before the first time
+var practiceRounds int
+var practiceGap int
+
+// the largest values accepted for '--rounds' and '--gap'
+const maxRounds = 20
+const maxGap = 3600
+
+// practiceFlags adds the flags used by the 'practice' sub command.
+func practiceFlags(fs *flag.FlagSet) {
+	fs.IntVar(&practiceRounds, "rounds", 4, fmt.Sprintf("\tUSE: '--rounds #' where # is the number of times to type the password, from 1 to %d [DEFAULT: 4]", maxRounds))
+	fs.IntVar(&practiceGap, "gap", 10, fmt.Sprintf("\tUSE: '--gap #' where # is the seconds to wait before the first round, doubled for each round after, from 1 to %d [DEFAULT: 10]", maxGap))
+}
+
+// practiceSession holds the terminal used to practise a password: key
+// presses arrive on 'keys', and the screen is drawn on 'out'.
+type practiceSession struct {
+	out  io.Writer
+	keys <-chan string
+}
+
+// runPractice handles the 'practice' sub command, which helps a password be
+// remembered. The password is read without echo, and then asked for a few
+// times with longer waits between each, showing which words were wrong.
+// Everything is shown on the alternate screen, which is cleared at the end,
+// so the password never reaches the scrollback, and it is never written to
+// disk.
+func runPractice(fs *flag.FlagSet) int {
+	if err := checkRange("rounds", practiceRounds, 1, maxRounds); err != nil {
+		return fail(err)
+	}
+	if err := checkRange("gap", practiceGap, 1, maxGap); err != nil {
+		return fail(err)
+	}
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+		return fail(usageErrorf("'practice' needs a terminal for both input and output"))
+	}
+	restore, err := rawTerminal()
+	if err != nil {
+		return fail(err)
+	}
+	fmt.Print(altScreenOn)
+
+	// read key presses in the background, so a wait can be cut short
+	keys := make(chan string)
+	go func() {
+		kr := &keyReader{r: os.Stdin}
+		for {
+			key, err := kr.readKey()
+			if err != nil {
+				close(keys)
+				return
+			}
+			keys <- key
+		}
+	}()
+
+	s := &practiceSession{out: os.Stdout, keys: keys}
+	correct, rounds := s.run()
+
+	fmt.Print(clearScreen + altScreenOff)
+	restore()
+	if rounds == 0 {
+		fmt.Printf("Practice stopped\n")
+		return exitOK
+	}
+	fmt.Printf("Practice finished: the password was typed correctly %d of %d times\n", correct, rounds)
+	return exitOK
+}
+
+// run reads the password, then asks for it in each round. It returns the
+// number of rounds typed correctly, and the number of rounds completed.
+func (s *practiceSession) run() (correct, rounds int) {
+	s.screen("Type the password to practise - it is not shown as you type.\r\n")
+	password, ok := s.readLine("Password: ")
+	if !ok || password == "" {
+		return 0, 0
+	}
+	gap := time.Duration(practiceGap) * time.Second
+	for round := 1; round <= practiceRounds; round++ {
+		if !s.wait(gap, round) {
+			return correct, rounds
+		}
+		s.screen(fmt.Sprintf("Round %d of %d - type the password from memory.\r\n", round, practiceRounds))
+		typed, ok := s.readLine("Password: ")
+		if !ok {
+			return correct, rounds
+		}
+		rounds++
+		mistakes := wordFeedback(password, typed)
+		var msg strings.Builder
+		if len(mistakes) == 0 {
+			correct++
+			fmt.Fprintf(&msg, "Round %d of %d - correct!\r\n", round, practiceRounds)
+		} else {
+			fmt.Fprintf(&msg, "Round %d of %d - not quite:\r\n", round, practiceRounds)
+			for _, m := range mistakes {
+				fmt.Fprintf(&msg, "\t» %s\r\n", m)
+			}
+		}
+		fmt.Fprintf(&msg, "\r\nPress any key to continue.\r\n")
+		s.screen(msg.String())
+		if key, ok := <-s.keys; !ok || key == "ctrl-c" || key == "esc" {
+			return correct, rounds
+		}
+		gap *= 2
+	}
+	return correct, rounds
+}
+
+// screen clears the screen and shows the text given.
+func (s *practiceSession) screen(text string) {
+	fmt.Fprintf(s.out, "%s\t\t%s - PRACTICE\r\n\r\n%s", clearScreen, pg.Title, text)
+}
+
+// readLine reads a line typed without echo. It returns false if the
+// practice is stopped with Ctrl-C or Esc.
+func (s *practiceSession) readLine(prompt string) (string, bool) {
+	fmt.Fprintf(s.out, "\r\n%s", prompt)
+	var line []rune
+	for key := range s.keys {
+		switch key {
+		case "enter":
+			fmt.Fprintf(s.out, "\r\n")
+			return string(line), true
+		case "ctrl-c", "esc":
+			return "", false
+		case "backspace":
+			if len(line) > 0 {
+				line = line[:len(line)-1]
+			}
+		case "up", "down", "left", "right":
+		default:
+			line = append(line, []rune(key)...)
+		}
+	}
+	return "", false
+}
+
+// wait counts down before the round, and returns false if the practice is
+// stopped. Any other key starts the round straight away.
+func (s *practiceSession) wait(d time.Duration, round int) bool {
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+	for left := d; left > 0; left -= time.Second {
+		s.screen(fmt.Sprintf("Round %d of %d starts in %s - try to recall the password meanwhile.\r\n\r\nPress any key to start now, or Esc to stop.\r\n", round, practiceRounds, left))
+		select {
+		case key, ok := <-s.keys:
+			if !ok || key == "ctrl-c" || key == "esc" {
+				return false
+			}
+			return true
+		case <-tick.C:
+		}
+	}
+	return true
+}
+
+// wordFeedback compares the password typed with the password, one word at
+// a time, and returns a description of each word that is wrong - or
+// nothing if the password was typed correctly.
+func wordFeedback(password, typed string) []string {
+	if password == typed {
+		return nil
+	}
+	want := practiceWords(password)
+	got := splitLike(typed, want, password)
+	var mistakes []string
+	for i, w := range want {
+		g := ""
+		if i < len(got) {
+			g = got[i]
+		}
+		switch {
+		case g == w:
+		case g == "":
+			mistakes = append(mistakes, fmt.Sprintf("word %d is missing - it is '%s'", i+1, w))
+		case strings.EqualFold(g, w):
+			mistakes = append(mistakes, fmt.Sprintf("word %d has the wrong case - typed '%s', it is '%s'", i+1, g, w))
+		default:
+			mistakes = append(mistakes, fmt.Sprintf("word %d is wrong - typed '%s', it is '%s'", i+1, g, w))
+		}
+	}
+	if len(got) > len(want) {
+		mistakes = append(mistakes, fmt.Sprintf("extra text typed at the end: '%s'", strings.Join(got[len(want):], "")))
+	}
+	if len(mistakes) == 0 {
+		// the words match, so the difference is in the separators
+		mistakes = append(mistakes, "the words are right, but the separators between them are not")
+	}
+	return mistakes
+}
+
+// isSeparator returns true for the characters used between words.
+func isSeparator(r rune) bool {
+	return r == ' ' || r == '-' || r == '.' || r == '_'
+}
+
+// practiceWords splits a password into its words, as paintWords does, but
+// without the separators between them. Any digits and symbols between the
+// words stay with the word before them.
+func practiceWords(password string) []string {
+	var words []string
+	for _, word := range paintWords(password) {
+		if word = strings.TrimFunc(word, isSeparator); word != "" {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// splitLike splits the typed password in the same way as the password was
+// split into the words given: into its own words if the password has
+// separators, or otherwise at the same places, with anything left over
+// returned as one more word.
+func splitLike(typed string, words []string, password string) []string {
+	if strings.IndexFunc(password, isSeparator) >= 0 {
+		return practiceWords(typed)
+	}
+	var got []string
+	for _, w := range words {
+		if typed == "" {
+			break
+		}
+		n := utf8.RuneCountInString(w)
+		runes := []rune(typed)
+		if n > len(runes) {
+			n = len(runes)
+		}
+		got = append(got, string(runes[:n]))
+		typed = string(runes[n:])
+	}
+	if typed != "" {
+		got = append(got, typed)
+	}
+	return got
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPracticeWords(t *testing.T) {
+	tests := []struct {
+		password string
+		want     []string
+	}{
+		{"cat dog pig", []string{"cat", "dog", "pig"}},
+		{"cat-dog-pig", []string{"cat", "dog", "pig"}},
+		{"catdogpig", []string{"cat", "dog", "pig"}},
+		{"ado7auf2one7gid", []string{"ado7", "auf2", "one7", "gid"}},
+		{"cat-dog7pig", []string{"cat", "dog7", "pig"}},
+	}
+	for _, tt := range tests {
+		if got := practiceWords(tt.password); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("practiceWords(%q) = %q, want %q", tt.password, got, tt.want)
+		}
+	}
+}
+
+func TestWordFeedback(t *testing.T) {
+	tests := []struct {
+		password string
+		typed    string
+		want     []string
+	}{
+		{"cat dog pig", "cat dog pig", nil},
+		{"cat dog pig", "cat dig pig", []string{"word 2 is wrong - typed 'dig', it is 'dog'"}},
+		{"cat dog pig", "cat Dog", []string{"word 2 has the wrong case - typed 'Dog', it is 'dog'", "word 3 is missing - it is 'pig'"}},
+		{"cat dog pig", "catdogpig", []string{"the words are right, but the separators between them are not"}},
+		{"ado7auf2one7gid", "ado7auf3one7gid", []string{"word 2 is wrong - typed 'auf3', it is 'auf2'"}},
+		{"ado7auf2one7gid", "ado7auf2one7gidx", []string{"extra text typed at the end: 'x'"}},
+		{"cat-dog7pig", "cat-dog7-pig", []string{"the words are right, but the separators between them are not"}},
+	}
+	for _, tt := range tests {
+		got := wordFeedback(tt.password, tt.typed)
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("wordFeedback(%q, %q) = %q, want %q", tt.password, tt.typed, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	pg "github.com/wiremoons/passgen/lib"
+)
+
+// provisionIn is set by '--in' to the CSV file of users to make passwords
+// for, and provisionFormat by '--format' to the output wanted. The hashes
+// are chosen with '--hash' and '--no-plaintext', as for 'generate'.
+var provisionIn string
+var provisionFormat string
+
+// the most users accepted in one batch
+const maxProvision = 100000
+
+// provisionFlags adds the flags used by the 'provision' sub command.
+func provisionFlags(fs *flag.FlagSet) {
+	fs.StringVar(&provisionIn, "in", "", "\tUSE: '--in PATH' read the users from the CSV file PATH, or '-' for stdin - required")
+	fs.StringVar(&provisionFormat, "format", "csv", "\tUSE: '--format csv|lines' output CSV, or 'username:password' lines - or 'username:hash' with one '--hash' format and '--no-plaintext' - for chpasswd and htpasswd files [DEFAULT: csv]")
+	hashFlags(fs)
+	fs.IntVar(&numwords, "w", 3, fmt.Sprintf("\tUSE: '-w #' where # is the number of three letter words to use, from 1 to %d [DEFAULT: 3]", maxWords))
+	fs.BoolVar(&passcase, "c", false, "\tUSE: '-c' mixed case passwords [DEFAULT: lowercase]")
+	fs.BoolVar(&remove, "r", false, "\tUSE: '-r' remove password spaces [DEFAULT: with spaces]")
+	fs.StringVar(&outPath, "out", "", "\tUSE: '--out PATH' write the CSV to the file PATH, readable only by you, instead of stdout")
+	fs.BoolVar(&outForce, "force", false, "\tUSE: '--force' with '--out' replace a file that already exists [DEFAULT: refuse]")
+	nonTTYFlags(fs)
+	policyFlags(fs)
+}
+
+// provisionUser is one row of the input: a user name, and the policy their
+// password must meet - or "" for the policy chosen with the options.
+type provisionUser struct {
+	name   string
+	policy string
+}
+
+// provisionSource makes the passwords for one policy, and holds the bits of
+// entropy each has.
+type provisionSource struct {
+	next    func() (string, error)
+	entropy float64
+}
+
+// runProvision handles the 'provision' sub command, which reads a CSV file
+// of user names - each with an optional policy name - and outputs a CSV
+// file of user name, password, entropy and any hashes chosen with '--hash'.
+// No password is used twice in a batch. The exit code to use is returned.
+func runProvision(fs *flag.FlagSet) int {
+	if provisionIn == "" {
+		return fail(usageErrorf("the users to make passwords for are needed - use '--in PATH'"))
+	}
+	if err := checkRange("w", numwords, 1, maxWords); err != nil {
+		return fail(err)
+	}
+	hashes, err := selectHashes(hashList)
+	if err != nil {
+		return fail(err)
+	}
+	switch provisionFormat {
+	case "csv":
+		if noPlaintext && len(hashes) == 0 {
+			return fail(usageErrorf("'--no-plaintext' would leave no passwords - use it with '--hash'"))
+		}
+	case "lines":
+		if len(hashes) > 1 {
+			return fail(usageErrorf("'--format lines' holds one password or hash for each user - use just one '--hash' format"))
+		}
+		if noPlaintext && len(hashes) == 0 {
+			return fail(usageErrorf("'--no-plaintext' would leave no passwords - use it with '--hash'"))
+		}
+		// the hash takes the place of the password, so it must be asked for
+		if len(hashes) == 1 && !noPlaintext {
+			return fail(usageErrorf("'--format lines' with '--hash' would lose the passwords - add '--no-plaintext' to output just the hashes, or use '--format csv'"))
+		}
+	default:
+		return fail(usageErrorf("'--format' must be 'csv' or 'lines' - not '%s'", provisionFormat))
+	}
+	if outPath != "" && !outForce {
+		if _, err := os.Lstat(outPath); err == nil {
+			return fail(ioErrorf("'%s' already exists - use '--force' to replace it", outPath))
+		}
+	}
+	users, err := readProvisionUsers()
+	if err != nil {
+		return fail(err)
+	}
+
+	// no password is repeated in the batch, whatever policy it was made for
+	unique = true
+	sources := map[string]provisionSource{}
+	var data bytes.Buffer
+	w := csv.NewWriter(&data)
+	if provisionFormat == "csv" {
+		header := []string{"username"}
+		if !noPlaintext {
+			header = append(header, "password")
+		}
+		header = append(header, "entropy")
+		for _, h := range hashes {
+			header = append(header, h.name)
+		}
+		w.Write(header)
+	}
+	for _, user := range users {
+		source, ok := sources[user.policy]
+		if !ok {
+			if source, err = newProvisionSource(user.policy); err != nil {
+				return fail(err)
+			}
+			sources[user.policy] = source
+		}
+		password, err := source.next()
+		if err != nil {
+			return fail(err)
+		}
+		var hashed []string
+		for _, h := range hashes {
+			hash, err := h.hash(password)
+			if err != nil {
+				return fail(fmt.Errorf("unable to hash the password for '%s' with %s: %s", user.name, h.name, err))
+			}
+			hashed = append(hashed, hash)
+		}
+		if provisionFormat == "lines" {
+			// the format read by chpasswd, and used in htpasswd files
+			if noPlaintext {
+				password = hashed[0]
+			}
+			fmt.Fprintf(&data, "%s:%s\n", user.name, password)
+			continue
+		}
+		row := []string{user.name}
+		if !noPlaintext {
+			row = append(row, password)
+		}
+		row = append(row, fmt.Sprintf("%.1f", source.entropy))
+		w.Write(append(row, hashed...))
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fail(err)
+	}
+
+	if outPath == "" {
+		if err := guardStdout(); err != nil {
+			return fail(err)
+		}
+		os.Stdout.Write(data.Bytes())
+		return exitOK
+	}
+	if err := writeSecretFile(outPath, data.Bytes(), outForce); err != nil {
+		return fail(err)
+	}
+	fmt.Fprintf(os.Stderr, "» Wrote %d user(s) to '%s' with mode 0600\n", len(users), outPath)
+	return exitOK
+}
+
+// newProvisionSource returns the source of passwords for the policy named,
+// or for the policy chosen with the options if the name is "".
+func newProvisionSource(name string) (provisionSource, error) {
+	var policy pg.Policy
+	var usePolicy bool
+	var err error
+	if name == "" {
+		policy, usePolicy, err = selectPolicy()
+	} else {
+		policy, err = loadPolicy(name)
+		usePolicy = true
+	}
+	if err != nil {
+		return provisionSource{}, err
+	}
+	source := provisionSource{entropy: pg.Plan{Words: numwords, Mixed: passcase}.Entropy()}
+	if usePolicy {
+		plan, err := planPolicy(policy)
+		if err != nil {
+			return source, err
+		}
+		source.entropy = plan.Entropy()
+	}
+	source.next, err = passwordSource(policy, usePolicy)
+	return source, err
+}
+
+// readProvisionUsers reads the users from the CSV file given with '--in'.
+// Each row holds a user name, and optionally the name of the policy their
+// password must meet. A first row starting 'username' is taken as a header
+// and skipped, as are lines starting with '#'.
+func readProvisionUsers() ([]provisionUser, error) {
+	var in io.Reader = os.Stdin
+	source := "stdin"
+	if provisionIn != "-" {
+		source = provisionIn
+		f, err := os.Open(provisionIn)
+		if err != nil {
+			return nil, ioErrorf("unable to read the users: %s", err)
+		}
+		defer f.Close()
+		in = f
+	}
+	r := csv.NewReader(in)
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	var users []provisionUser
+	names := map[string]bool{}
+	for row := 1; ; row++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, usageErrorf("unable to read the users: %s", err)
+		}
+		if row == 1 && strings.EqualFold(strings.TrimSpace(record[0]), "username") {
+			continue
+		}
+		if len(record) > 2 {
+			return nil, usageErrorf("%s row %d: expected a user name and an optional policy name, not %d fields", source, row, len(record))
+		}
+		user := provisionUser{name: strings.TrimSpace(record[0])}
+		if len(record) == 2 {
+			user.policy = strings.TrimSpace(record[1])
+		}
+		if user.name == "" {
+			return nil, usageErrorf("%s row %d: the user name is empty", source, row)
+		}
+		if provisionFormat == "lines" && strings.Contains(user.name, ":") {
+			return nil, usageErrorf("%s row %d: the user name '%s' can not hold a ':' with '--format lines'", source, row, user.name)
+		}
+		if names[user.name] {
+			return nil, usageErrorf("%s row %d: the user name '%s' is given more than once", source, row, user.name)
+		}
+		names[user.name] = true
+		users = append(users, user)
+		if len(users) > maxProvision {
+			return nil, usageErrorf("at most %d users can be provisioned at once", maxProvision)
+		}
+	}
+	if len(users) == 0 {
+		return nil, usageErrorf("no users found in %s", source)
+	}
+	return users, nil
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pg "github.com/wiremoons/passgen/lib"
+)
+
+// runCommand runs the command with the arguments given, as if from the
+// command line, without reading the configuration file or environment of
+// the user running the tests. The exit code is returned.
+func runCommand(t *testing.T, name string, args ...string) int {
+	home := t.TempDir()
+	for _, env := range []string{"HOME", "XDG_CONFIG_HOME", "PASSGEN_CONFIG"} {
+		old, set := os.LookupEnv(env)
+		os.Setenv(env, home)
+		if env == "PASSGEN_CONFIG" {
+			os.Setenv(env, filepath.Join(home, "missing.toml"))
+		}
+		defer func(env string) {
+			if set {
+				os.Setenv(env, old)
+			} else {
+				os.Unsetenv(env)
+			}
+		}(env)
+	}
+	for _, s := range settings {
+		if value, set := os.LookupEnv(s.env()); set {
+			os.Unsetenv(s.env())
+			defer os.Setenv(s.env(), value)
+		}
+	}
+	return findCommand(name).execute(args)
+}
+
+// writeUsers writes the CSV file of users given, and returns its path.
+func writeUsers(t *testing.T, data string) string {
+	path := filepath.Join(t.TempDir(), "users.csv")
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// readCSV returns the rows of the CSV file at path.
+func readCSV(t *testing.T, path string) [][]string {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatalf("the output is not valid CSV: %v", err)
+	}
+	return rows
+}
+
+func TestProvisionCSVRoundTrip(t *testing.T) {
+	in := writeUsers(t, `username,policy
+# a comment line
+alice
+ bob, pci-dss
+"smith, john"
+"o""brien",
+"élodie"
+`)
+	out := filepath.Join(t.TempDir(), "passwords.csv")
+	if code := runCommand(t, "provision", "--in", in, "--hash", "sha512-crypt", "-w", "4", "--out", out); code != exitOK {
+		t.Fatalf("provision exited with %d", code)
+	}
+	info, err := os.Stat(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("output file mode %v, want 0600", info.Mode().Perm())
+	}
+
+	rows := readCSV(t, out)
+	if got := strings.Join(rows[0], ","); got != "username,password,entropy,sha512-crypt" {
+		t.Errorf("header = %s", got)
+	}
+	users := []string{"alice", "bob", "smith, john", `o"brien`, "élodie"}
+	if len(rows) != len(users)+1 {
+		t.Fatalf("%d rows, want %d", len(rows), len(users)+1)
+	}
+	passwords := map[string]bool{}
+	for i, row := range rows[1:] {
+		if len(row) != 4 {
+			t.Errorf("row %d has %d fields: %q", i+1, len(row), row)
+			continue
+		}
+		name, password, entropy, hash := row[0], row[1], row[2], row[3]
+		if name != users[i] {
+			t.Errorf("row %d user name %q, want %q", i+1, name, users[i])
+		}
+		if password == "" || passwords[password] {
+			t.Errorf("row %d password %q is empty or repeated", i+1, password)
+		}
+		passwords[password] = true
+		// the hash is found again from the password and its salt
+		if fields := strings.Split(hash, "$"); len(fields) != 4 || pg.SHA512Crypt(password, fields[2], pg.SHA512CryptRounds) != hash {
+			t.Errorf("row %d sha512-crypt %s does not match the password", i+1, hash)
+		}
+		if entropy == "" || entropy == "0.0" {
+			t.Errorf("row %d entropy %q", i+1, entropy)
+		}
+	}
+
+	// bob's password meets the pci-dss policy, and the others are four words
+	policy, err := loadPolicy("pci-dss")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if broken := policy.Check(rows[2][1]); len(broken) > 0 {
+		t.Errorf("bob's password %q breaks the pci-dss policy: %q", rows[2][1], broken)
+	}
+	if words := strings.Fields(rows[1][1]); len(words) != 4 {
+		t.Errorf("alice's password %q does not have four words", rows[1][1])
+	}
+	if want := fmt.Sprintf("%.1f", pg.Plan{Words: 4}.Entropy()); rows[1][2] != want {
+		t.Errorf("alice's entropy %s, want %s", rows[1][2], want)
+	}
+}
+
+func TestProvisionInputErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		args  []string
+		code  int
+	}{
+		{"duplicate", "alice\nbob\nalice\n", nil, exitUsage},
+		{"empty name", "alice\n\"\"\n", nil, exitUsage},
+		{"too many fields", "alice,pci-dss,extra\n", nil, exitUsage},
+		{"only a header", "username,policy\n", nil, exitUsage},
+		{"unknown policy", "alice,no-such-policy\n", nil, exitUsage},
+		{"unknown hash", "alice\n", []string{"--hash", "md5"}, exitUsage},
+		{"bad format", "alice\n", []string{"--format", "json"}, exitUsage},
+	}
+	for _, tt := range tests {
+		out := filepath.Join(t.TempDir(), "passwords.csv")
+		args := append([]string{"--in", writeUsers(t, tt.input), "--out", out}, tt.args...)
+		if code := runCommand(t, "provision", args...); code != tt.code {
+			t.Errorf("%s: provision exited with %d, want %d", tt.name, code, tt.code)
+		}
+		if _, err := os.Stat(out); err == nil {
+			t.Errorf("%s: the output file was written", tt.name)
+		}
+	}
+}
+
+func TestProvisionLines(t *testing.T) {
+	in := writeUsers(t, "alice\nbob\n")
+	tests := []struct {
+		args   []string
+		prefix string // the start of each password or hash
+		code   int
+	}{
+		{[]string{"-w", "3"}, "", exitOK},
+		{[]string{"--hash", "htpasswd", "--no-plaintext"}, "$2y$", exitOK},
+		{[]string{"--hash", "sha512-crypt", "--no-plaintext"}, "$6$", exitOK},
+		// the hash would otherwise replace the password
+		{[]string{"--hash", "htpasswd"}, "", exitUsage},
+		{[]string{"--hash", "bcrypt,htpasswd", "--no-plaintext"}, "", exitUsage},
+		{[]string{"--no-plaintext"}, "", exitUsage},
+	}
+	for _, tt := range tests {
+		out := filepath.Join(t.TempDir(), "passwords.txt")
+		args := append([]string{"--in", in, "--format", "lines", "--out", out}, tt.args...)
+		if code := runCommand(t, "provision", args...); code != tt.code {
+			t.Errorf("%q: provision exited with %d, want %d", tt.args, code, tt.code)
+			continue
+		}
+		if tt.code != exitOK {
+			continue
+		}
+		data, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("%q: %d lines, want 2", tt.args, len(lines))
+		}
+		for i, name := range []string{"alice", "bob"} {
+			fields := strings.SplitN(lines[i], ":", 2)
+			if len(fields) != 2 || fields[0] != name || !strings.HasPrefix(fields[1], tt.prefix) {
+				t.Errorf("%q: line %q, want '%s:%s...'", tt.args, lines[i], name, tt.prefix)
+			}
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pg "github.com/wiremoons/passgen/lib"
+)
+
+// qrShow is set by '--qr' to show the password as a QR code in the
+// terminal, and qrFile by '--qr-file' to write the QR code to a PNG or SVG
+// file
+var qrShow bool
+var qrFile string
+
+// the quiet zone around a QR code, in modules, and the pixels per module
+// in the image files
+const qrQuiet = 4
+const qrScale = 8
+
+// checkQR returns an error if '--qr' or '--qr-file' can not be used with
+// the other options given: there must be just one password to encode.
+func checkQR() error {
+	if !qrShow && qrFile == "" {
+		return nil
+	}
+	if outPath != "" {
+		return usageErrorf("'--qr' and '--qr-file' can not be used with '--out'")
+	}
+	if !quiet && !interactive {
+		return usageErrorf("'--qr' and '--qr-file' need a single password - use them with '-q' or '-i'")
+	}
+	if quiet && quietCount() > 1 {
+		return usageErrorf("'--qr' and '--qr-file' can only encode one password - do not use '-s' with '-q'")
+	}
+	return checkQRFile()
+}
+
+// checkQRFile returns an error if the file given with '--qr-file' is not a
+// PNG or SVG file, or already exists without '--force'.
+func checkQRFile() error {
+	if qrFile == "" {
+		return nil
+	}
+	switch strings.ToLower(filepath.Ext(qrFile)) {
+	case ".png", ".svg":
+	default:
+		return usageErrorf("'--qr-file' must name a '.png' or '.svg' file - not '%s'", qrFile)
+	}
+	// checked now, so no password is output if the file can not be written
+	if _, err := os.Lstat(qrFile); err == nil && !outForce {
+		return ioErrorf("'%s' already exists - use '--force' to replace it", qrFile)
+	}
+	return nil
+}
+
+// outputQR shows the text - usually the password - as a QR code in the
+// terminal with '--qr', and writes it to the file given with '--qr-file',
+// readable only by its owner. It does nothing if neither option is used.
+func outputQR(text string) error {
+	if !qrShow && qrFile == "" {
+		return nil
+	}
+	code, err := pg.EncodeQR([]byte(text), pg.QRLevelM)
+	if err != nil {
+		return err
+	}
+	if qrShow {
+		fmt.Print(code.HalfBlocks(qrQuiet))
+	}
+	if qrFile == "" {
+		return nil
+	}
+	var data bytes.Buffer
+	if strings.ToLower(filepath.Ext(qrFile)) == ".svg" {
+		err = code.SVG(&data, qrScale, qrQuiet)
+	} else {
+		err = code.PNG(&data, qrScale, qrQuiet)
+	}
+	if err != nil {
+		return ioErrorf("unable to make the QR code image: %s", err)
+	}
+	if err := writeSecretFile(qrFile, data.Bytes(), outForce); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "» Wrote the QR code to '%s' with mode 0600\n", qrFile)
+	return nil
+}
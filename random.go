@@ -0,0 +1,37 @@
+package main
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"math/rand"
+	"os"
+)
+
+// rng is used to choose the words and characters of every password. It
+// reads from the secure random number generator of the operating system,
+// so passwords made in separate runs - even in the same instant, from a
+// shell loop - are never related, as they could be when seeded from the
+// clock.
+var rng = rand.New(cryptoSource{})
+
+// cryptoSource is a math/rand source that reads from crypto/rand, so the
+// helpers of math/rand, such as Intn and Shuffle, can be used with it. It
+// can not be seeded.
+type cryptoSource struct{}
+
+// Int63 returns a random number from 0 up to 2^63.
+func (s cryptoSource) Int63() int64 {
+	return int64(s.Uint64() & (1<<63 - 1))
+}
+
+// Uint64 returns a random 64 bit number.
+func (cryptoSource) Uint64() uint64 {
+	var b [8]byte
+	if _, err := crand.Read(b[:]); err != nil {
+		os.Exit(fail(ioErrorf("unable to read random numbers: %s", err)))
+	}
+	return binary.BigEndian.Uint64(b[:])
+}
+
+// Seed does nothing, as the numbers do not come from a seed.
+func (cryptoSource) Seed(int64) {}
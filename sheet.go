@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pg "github.com/wiremoons/passgen/lib"
+)
+
+// sheetNames and sheetNamesFile give the names of the people or accounts
+// to make a password for, sheetFormat the page to output - "html" or "svg"
+// - and sheetTitle the heading printed at the top of the page
+var sheetNames string
+var sheetNamesFile string
+var sheetFormat string
+var sheetTitle string
+
+// the most names accepted for one sheet
+const maxSheetNames = 1000
+
+// sizes used to lay out an SVG sheet, in millimetres on an A4 page
+const (
+	sheetWidth    = 210.0
+	sheetHeight   = 297.0
+	sheetMargin   = 10.0
+	sheetQRSize   = 36.0
+	sheetLineChar = 70 // characters on each line of the phonetic spelling
+)
+
+// sheetFlags adds the flags used by the 'sheet' sub command.
+func sheetFlags(fs *flag.FlagSet) {
+	fs.StringVar(&sheetNames, "names", "", "\tUSE: '--names NAME,NAME' make a password for each name given")
+	fs.StringVar(&sheetNamesFile, "names-file", "", "\tUSE: '--names-file PATH' make a password for each name in the file PATH, one per line - or '-' for stdin")
+	fs.StringVar(&sheetFormat, "format", "", "\tUSE: '--format html|svg' the page to output [DEFAULT: from the '--out' file name, or html]")
+	fs.StringVar(&sheetTitle, "title", "Credentials", "\tUSE: '--title TEXT' the heading printed at the top of the page [DEFAULT: Credentials]")
+	fs.BoolVar(&qrShow, "qr", false, "\tUSE: '--qr' add a QR code of each password, to scan with a phone [DEFAULT: no QR codes]")
+	fs.IntVar(&numwords, "w", 3, fmt.Sprintf("\tUSE: '-w #' where # is the number of three letter words to use, from 1 to %d [DEFAULT: 3]", maxWords))
+	fs.BoolVar(&passcase, "c", false, "\tUSE: '-c' mixed case passwords [DEFAULT: lowercase]")
+	fs.BoolVar(&remove, "r", false, "\tUSE: '-r' remove password spaces [DEFAULT: with spaces]")
+	fs.StringVar(&outPath, "out", "", "\tUSE: '--out PATH' write the page to the file PATH, readable only by you, instead of stdout")
+	fs.BoolVar(&outForce, "force", false, "\tUSE: '--force' with '--out' replace a file that already exists [DEFAULT: refuse]")
+	nonTTYFlags(fs)
+	policyFlags(fs)
+}
+
+// sheetEntry is one name on a sheet, with its password.
+type sheetEntry struct {
+	name     string
+	password string
+	qr       *pg.QRCode // nil without '--qr'
+}
+
+// runSheet handles the 'sheet' sub command, which makes a password for
+// each name given, and outputs a page to print and cut up: each entry has
+// the name, the password in a large font with space between its words, the
+// phonetic spelling of the password, and with '--qr' a QR code of it. The
+// exit code to use is returned.
+func runSheet(fs *flag.FlagSet) int {
+	if err := checkRange("w", numwords, 1, maxWords); err != nil {
+		return fail(err)
+	}
+	format, err := sheetPageFormat()
+	if err != nil {
+		return fail(err)
+	}
+	names, err := readSheetNames()
+	if err != nil {
+		return fail(err)
+	}
+	if outPath != "" && !outForce {
+		if _, err := os.Lstat(outPath); err == nil {
+			return fail(ioErrorf("'%s' already exists - use '--force' to replace it", outPath))
+		}
+	}
+
+	policy, usePolicy, err := selectPolicy()
+	if err != nil {
+		return fail(err)
+	}
+	// everyone on the sheet gets a different password
+	unique = true
+	next, err := passwordSource(policy, usePolicy)
+	if err != nil {
+		return fail(err)
+	}
+	var entries []sheetEntry
+	for _, name := range names {
+		password, err := next()
+		if err != nil {
+			return fail(err)
+		}
+		entry := sheetEntry{name: name, password: password}
+		if qrShow {
+			if entry.qr, err = pg.EncodeQR([]byte(password), pg.QRLevelM); err != nil {
+				return fail(err)
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	var page bytes.Buffer
+	if format == "svg" {
+		sheetSVG(&page, entries)
+	} else {
+		sheetHTML(&page, entries)
+	}
+	if outPath == "" {
+		if err := guardStdout(); err != nil {
+			return fail(err)
+		}
+		os.Stdout.Write(page.Bytes())
+		return exitOK
+	}
+	if err := writeSecretFile(outPath, page.Bytes(), outForce); err != nil {
+		return fail(err)
+	}
+	fmt.Fprintf(os.Stderr, "» Wrote a sheet of %d password(s) to '%s' with mode 0600\n", len(entries), outPath)
+	return exitOK
+}
+
+// sheetPageFormat returns the page format to output: the one chosen with
+// '--format', or the one matching the '--out' file name, or "html".
+func sheetPageFormat() (string, error) {
+	format := strings.ToLower(sheetFormat)
+	if format == "" {
+		format = "html"
+		if strings.ToLower(filepath.Ext(outPath)) == ".svg" {
+			format = "svg"
+		}
+	}
+	if format != "html" && format != "svg" {
+		return "", usageErrorf("'--format' must be 'html' or 'svg' - not '%s'", sheetFormat)
+	}
+	return format, nil
+}
+
+// readSheetNames returns the names given with '--names' and '--names-file'.
+// Blank lines, and lines starting with '#', in the file are skipped.
+func readSheetNames() ([]string, error) {
+	var names []string
+	for _, name := range strings.Split(sheetNames, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	if sheetNamesFile != "" {
+		var r io.Reader = os.Stdin
+		if sheetNamesFile != "-" {
+			f, err := os.Open(sheetNamesFile)
+			if err != nil {
+				return nil, ioErrorf("unable to read the names: %s", err)
+			}
+			defer f.Close()
+			r = f
+		}
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			if name := strings.TrimSpace(scanner.Text()); name != "" && !strings.HasPrefix(name, "#") {
+				names = append(names, name)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, ioErrorf("unable to read the names: %s", err)
+		}
+	}
+	if len(names) == 0 {
+		return nil, usageErrorf("no names given - use '--names NAME,NAME' or '--names-file PATH'")
+	}
+	if len(names) > maxSheetNames {
+		return nil, usageErrorf("at most %d names can be put on one sheet", maxSheetNames)
+	}
+	return names, nil
+}
+
+// sheetSpace is printed in place of each space in a password, so a space
+// that must be typed can be told apart from the gaps between the words.
+const sheetSpace = "␣"
+
+// sheetWords splits the password into the parts printed with a gap between
+// them: its words, each with the separators, digits and symbols that follow
+// it, and with each space shown as sheetSpace.
+func sheetWords(password string) []string {
+	var words []string
+	for _, word := range paintWords(password) {
+		words = append(words, strings.Replace(word, " ", sheetSpace, -1))
+	}
+	return words
+}
+
+// sheetHTML writes the sheet as an HTML page, laid out for A4 paper with
+// dashed cut lines between the entries.
+func sheetHTML(w io.Writer, entries []sheetEntry) {
+	title := html.EscapeString(sheetTitle)
+	fmt.Fprintf(w, "<!DOCTYPE html>\n<!-- generated by '%s sheet' -->\n", appname)
+	fmt.Fprintf(w, "<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n", title)
+	fmt.Fprintf(w, `<style>
+@page { size: A4; margin: 10mm; }
+body { font-family: Helvetica, Arial, sans-serif; margin: 0; color: #000; background: #fff; }
+h1 { font-size: 16pt; margin: 0 0 4mm 0; }
+.entry { display: flex; justify-content: space-between; align-items: center; gap: 6mm; padding: 5mm 4mm; border-bottom: 0.3mm dashed #000; break-inside: avoid; page-break-inside: avoid; }
+.entry:first-of-type { border-top: 0.3mm dashed #000; }
+.name { font-size: 12pt; font-weight: bold; }
+.password { font-family: "DejaVu Sans Mono", Menlo, Consolas, monospace; font-size: 24pt; margin: 3mm 0; overflow-wrap: anywhere; }
+.password span { margin-right: 0.6em; }
+.phonetic { font-size: 9pt; color: #333; }
+.qr { width: 30mm; height: 30mm; flex: none; }
+.note { font-size: 8pt; margin-top: 4mm; }
+</style>
+</head>
+<body>
+`)
+	fmt.Fprintf(w, "<h1>%s</h1>\n", title)
+	for _, e := range entries {
+		fmt.Fprintf(w, "<div class=\"entry\">\n<div>\n<div class=\"name\">%s</div>\n<div class=\"password\">", html.EscapeString(e.name))
+		for _, word := range sheetWords(e.password) {
+			fmt.Fprintf(w, "<span>%s</span>", html.EscapeString(word))
+		}
+		fmt.Fprintf(w, "</div>\n<div class=\"phonetic\">%s</div>\n</div>\n", html.EscapeString(pg.Phonetic(e.password)))
+		if e.qr != nil {
+			side := e.qr.Size + 2*qrQuiet
+			fmt.Fprintf(w, "<svg class=\"qr\" xmlns=\"http://www.w3.org/2000/svg\" viewBox=\"0 0 %d %d\" shape-rendering=\"crispEdges\"><rect width=\"100%%\" height=\"100%%\" fill=\"#fff\"/><path fill=\"#000\" d=\"%s\"/></svg>\n", side, side, e.qr.SVGPath(qrQuiet))
+		}
+		fmt.Fprintf(w, "</div>\n")
+	}
+	fmt.Fprintf(w, "<p class=\"note\">Cut along the dashed lines. Keep your password private, and destroy your slip once you have changed it or stored it safely.</p>\n")
+	fmt.Fprintf(w, "</body>\n</html>\n")
+}
+
+// sheetSVG writes the sheet as an SVG image the width of an A4 page, with
+// dashed cut lines between the entries. The page is made longer if the
+// entries do not fit on one.
+func sheetSVG(w io.Writer, entries []sheetEntry) {
+	// lay out the entries first, so the height of the page is known
+	type layout struct {
+		top, height float64
+		phonetic    []string
+		fontSize    float64
+	}
+	textWidth := sheetWidth - 2*sheetMargin - 4
+	if qrShow {
+		textWidth -= sheetQRSize + 4
+	}
+	var layouts []layout
+	y := sheetMargin + 12
+	for _, e := range entries {
+		l := layout{top: y, phonetic: wrapWords(pg.Phonetic(e.password), sheetLineChar)}
+		// monospaced characters are about 0.6 of the font size across, and
+		// each gap between words is about one character
+		words := sheetWords(e.password)
+		chars := float64(len([]rune(strings.Join(words, ""))) + len(words) - 1)
+		l.fontSize = math.Min(10, textWidth/(chars*0.6))
+		l.height = math.Max(sheetQRSize+8, 22+l.fontSize+4.5*float64(len(l.phonetic)))
+		layouts = append(layouts, l)
+		y += l.height
+	}
+	height := math.Max(sheetHeight, y+sheetMargin)
+
+	fmt.Fprintf(w, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<!-- generated by '%s sheet' -->\n", appname)
+	fmt.Fprintf(w, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%gmm\" height=\"%gmm\" viewBox=\"0 0 %g %g\">\n", sheetWidth, height, sheetWidth, height)
+	fmt.Fprintf(w, "<rect width=\"100%%\" height=\"100%%\" fill=\"#fff\"/>\n")
+	fmt.Fprintf(w, "<text x=\"%g\" y=\"%g\" font-family=\"Helvetica, Arial, sans-serif\" font-size=\"6\" font-weight=\"bold\">%s</text>\n", sheetMargin, sheetMargin+6, html.EscapeString(sheetTitle))
+	cut := func(y float64) {
+		fmt.Fprintf(w, "<line x1=\"%g\" y1=\"%g\" x2=\"%g\" y2=\"%g\" stroke=\"#000\" stroke-width=\"0.3\" stroke-dasharray=\"2 1.5\"/>\n", sheetMargin/2, y, sheetWidth-sheetMargin/2, y)
+	}
+	for i, e := range entries {
+		l := layouts[i]
+		x := sheetMargin + 2
+		cut(l.top)
+		fmt.Fprintf(w, "<text x=\"%g\" y=\"%g\" font-family=\"Helvetica, Arial, sans-serif\" font-size=\"5\" font-weight=\"bold\">%s</text>\n", x, l.top+10, html.EscapeString(e.name))
+		fmt.Fprintf(w, "<text x=\"%g\" y=\"%.1f\" font-family=\"DejaVu Sans Mono, Menlo, Consolas, monospace\" font-size=\"%.1f\">", x, l.top+14+l.fontSize, l.fontSize)
+		for j, word := range sheetWords(e.password) {
+			dx := 0.0
+			if j > 0 {
+				dx = l.fontSize * 0.6
+			}
+			fmt.Fprintf(w, "<tspan dx=\"%.1f\">%s</tspan>", dx, html.EscapeString(word))
+		}
+		fmt.Fprintf(w, "</text>\n")
+		fmt.Fprintf(w, "<text font-family=\"Helvetica, Arial, sans-serif\" font-size=\"3.2\" fill=\"#333\">")
+		for j, line := range l.phonetic {
+			fmt.Fprintf(w, "<tspan x=\"%g\" y=\"%.1f\">%s</tspan>", x, l.top+20+l.fontSize+4.5*float64(j), html.EscapeString(line))
+		}
+		fmt.Fprintf(w, "</text>\n")
+		if e.qr != nil {
+			side := float64(e.qr.Size + 2*qrQuiet)
+			fmt.Fprintf(w, "<g transform=\"translate(%g %.1f) scale(%.4f)\" shape-rendering=\"crispEdges\"><rect width=\"%g\" height=\"%g\" fill=\"#fff\"/><path fill=\"#000\" d=\"%s\"/></g>\n",
+				sheetWidth-sheetMargin-sheetQRSize, l.top+(l.height-sheetQRSize)/2, sheetQRSize/side, side, side, e.qr.SVGPath(qrQuiet))
+		}
+	}
+	cut(y)
+	fmt.Fprintf(w, "<text x=\"%g\" y=\"%g\" font-family=\"Helvetica, Arial, sans-serif\" font-size=\"3\">Cut along the dashed lines. Keep your password private, and destroy your slip once you have changed it or stored it safely.</text>\n", sheetMargin, y+6)
+	fmt.Fprintf(w, "</svg>\n")
+}
+
+// wrapWords splits the text into lines of at most 'width' characters,
+// breaking at spaces. A word longer than the width has a line to itself.
+func wrapWords(text string, width int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		if line != "" && len(line)+1+len(word) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
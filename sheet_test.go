@@ -0,0 +1,24 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSheetWords(t *testing.T) {
+	tests := []struct {
+		password string
+		want     []string
+	}{
+		// the spaces are shown, so the two can be told apart on paper
+		{"cat dog pig", []string{"cat␣", "dog␣", "pig"}},
+		{"catdogpig", []string{"cat", "dog", "pig"}},
+		{"ado7auf2one7gid", []string{"ado7", "auf2", "one7", "gid"}},
+		{"Cat-Dog.pig", []string{"Cat-", "Dog.", "pig"}},
+	}
+	for _, tt := range tests {
+		if got := sheetWords(tt.password); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("sheetWords(%q) = %q, want %q", tt.password, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// nonTTY is set by '--non-tty' to choose what happens when passwords are
+// written to stdout and it is not a terminal: "warn", "refuse" or "allow".
+// pipeOK is set by '--pipe' to allow it for one run, for pipelines.
+var nonTTY = "warn"
+var pipeOK bool
+
+// guardStdout checks where passwords written to stdout will end up. If
+// stdout is redirected to a file or pipe, where the passwords may be kept
+// in logs, a warning is shown on stderr, or an error returned, as chosen
+// with '--non-tty'. Nothing is checked if '--pipe' is used.
+func guardStdout() error {
+	switch nonTTY {
+	case "warn", "refuse", "allow":
+	default:
+		return usageErrorf("'--non-tty' must be one of 'warn', 'refuse' or 'allow' - not '%s'", nonTTY)
+	}
+	if pipeOK || nonTTY == "allow" || isTerminal(os.Stdout) {
+		return nil
+	}
+	if nonTTY == "refuse" {
+		return usageErrorf("refusing to write passwords to stdout, as it is not a terminal - use '--pipe' if this is intended, or '--out' to write a protected file")
+	}
+	fmt.Fprintf(os.Stderr, "WARNING: passwords are being written to a file or pipe, not a terminal, so may be kept in logs - use '--pipe' if this is intended, or '--out' to write a protected file\n")
+	return nil
+}
+
+// readSecret returns a password read from stdin. When stdin is a terminal
+// the prompt is shown on stderr and the password is not echoed as it is
+// typed, otherwise the first line of stdin is used. A usage error is
+// returned if no password is given.
+func readSecret(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return readPassword(os.Stdin)
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	secret, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", ioErrorf("unable to read the password from stdin: %s", err)
+	}
+	if len(secret) == 0 {
+		return "", usageErrorf("no password given on stdin")
+	}
+	return string(secret), nil
+}
+
+// readPassword returns the first line read from r, without the line ending.
+// An error is returned if there is no password to read.
+func readPassword(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", ioErrorf("unable to read the password from stdin: %s", err)
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return "", usageErrorf("no password given on stdin")
+	}
+	return line, nil
+}
+
+// ANSI escape sequences used to draw on the terminal
+const (
+	altScreenOn  = "\x1b[?1049h" // switch to the alternate screen buffer
+	altScreenOff = "\x1b[?1049l" // return to the normal screen buffer
+	clearScreen  = "\x1b[H\x1b[2J"
+	hideCursor   = "\x1b[?25l"
+	showCursor   = "\x1b[?25h"
+	reverseVideo = "\x1b[7m"
+	underlined   = "\x1b[4m"
+	resetStyle   = "\x1b[0m"
+)
+
+// isTerminal returns true if the file is a terminal.
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// rawTerminal puts the terminal on stdin into raw mode, so each key press
+// can be read as it is typed without being echoed. The function returned
+// restores the terminal to its previous state.
+func rawTerminal() (restore func(), err error) {
+	fd := int(os.Stdin.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, ioErrorf("unable to set up the terminal: %s", err)
+	}
+	return func() { term.Restore(fd, state) }, nil
+}
+
+// keyReader returns the keys pressed on a terminal in raw mode one at a
+// time, even when several arrive together - such as when text is pasted.
+type keyReader struct {
+	r   io.Reader
+	buf []byte
+}
+
+// readKey returns the next key pressed. Special keys are named: "up",
+// "down", "left", "right", "enter", "esc", "backspace" and "ctrl-c".
+// Other keys are returned as the character typed.
+func (k *keyReader) readKey() (string, error) {
+	if len(k.buf) == 0 {
+		buf := make([]byte, 64)
+		n, err := k.r.Read(buf)
+		if err != nil {
+			return "", err
+		}
+		k.buf = buf[:n]
+	}
+	key, n := parseKey(k.buf)
+	k.buf = k.buf[n:]
+	return key, nil
+}
+
+// parseKey returns the first key in the bytes read from the terminal, and
+// the number of bytes it used. No key is returned if there are no bytes.
+func parseKey(b []byte) (string, int) {
+	if len(b) == 0 {
+		return "", 0
+	}
+	switch {
+	case len(b) >= 3 && b[0] == 0x1b && (b[1] == '[' || b[1] == 'O'):
+		// escape sequences end with a byte from '@' to '~'
+		end := 2
+		for end < len(b) && (b[end] < 0x40 || b[end] > 0x7e) {
+			end++
+		}
+		if end == len(b) {
+			return "esc", len(b)
+		}
+		names := map[byte]string{'A': "up", 'B': "down", 'C': "right", 'D': "left"}
+		if end == 2 {
+			if name, ok := names[b[2]]; ok {
+				return name, 3
+			}
+		}
+		return "", end + 1
+	case b[0] == 0x1b:
+		return "esc", 1
+	case b[0] == '\r' && len(b) > 1 && b[1] == '\n':
+		return "enter", 2
+	case b[0] == '\r' || b[0] == '\n':
+		return "enter", 1
+	case b[0] == 0x7f || b[0] == 0x08:
+		return "backspace", 1
+	case b[0] == 0x03:
+		return "ctrl-c", 1
+	}
+	_, n := utf8.DecodeRune(b)
+	return string(b[:n]), n
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseKey(t *testing.T) {
+	tests := []struct {
+		in   string
+		key  string
+		used int
+	}{
+		{"", "", 0},
+		{"a", "a", 1},
+		{"ab", "a", 1},
+		{"é", "é", 2},
+		{"\r", "enter", 1},
+		{"\r\n", "enter", 2},
+		{"\n", "enter", 1},
+		{"\x7f", "backspace", 1},
+		{"\x08", "backspace", 1},
+		{"\x03", "ctrl-c", 1},
+		{"\x1b", "esc", 1},
+		{"\x1b[A", "up", 3},
+		{"\x1b[B", "down", 3},
+		{"\x1b[C", "right", 3},
+		{"\x1b[D", "left", 3},
+		{"\x1bOA", "up", 3},
+		{"\x1b[Ax", "up", 3},
+		{"\x1b[1;5C", "", 6},
+		{"\x1b[12", "esc", 4},
+	}
+	for _, tt := range tests {
+		key, used := parseKey([]byte(tt.in))
+		if key != tt.key || used != tt.used {
+			t.Errorf("parseKey(%q) = %q, %d, want %q, %d", tt.in, key, used, tt.key, tt.used)
+		}
+	}
+}
+
+// emptyReader returns no bytes, and no error, on its first read.
+type emptyReader struct {
+	read bool
+	r    *strings.Reader
+}
+
+func (e *emptyReader) Read(p []byte) (int, error) {
+	if !e.read {
+		e.read = true
+		return 0, nil
+	}
+	return e.r.Read(p)
+}
+
+func TestReadKey(t *testing.T) {
+	k := &keyReader{r: &emptyReader{r: strings.NewReader("x\x1b[Aq")}}
+	var keys []string
+	for {
+		key, err := k.readKey()
+		if err != nil {
+			break
+		}
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	if got, want := strings.Join(keys, ","), "x,up,q"; got != want {
+		t.Errorf("readKey returned %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+// verifySilent is set by '-q' to only set the exit code of 'verify'
+var verifySilent bool
+
+// verifyFlags adds the flags used by the 'verify' sub command.
+func verifyFlags(fs *flag.FlagSet) {
+	policyFlags(fs)
+	fs.BoolVar(&verifySilent, "q", false, "\tUSE: '-q' no output - just set the exit code")
+}
+
+// runVerify handles the 'verify' sub command, which reads a candidate
+// password from stdin and reports every rule of the chosen policy that
+// it breaks. The exit code is exitOK if the password meets the policy,
+// exitFail if it does not, or another code if the check could not be run.
+func runVerify(fs *flag.FlagSet) int {
+	policy, ok, err := selectPolicy()
+	if err != nil {
+		return fail(err)
+	}
+	if !ok {
+		return fail(usageErrorf("choose a policy to verify against with '--policy NAME' or '--pwquality'"))
+	}
+
+	password, err := readSecret("Password to verify: ")
+	if err != nil {
+		return fail(err)
+	}
+
+	broken := policy.Check(password)
+	if verifySilent {
+		if len(broken) > 0 {
+			return exitFail
+		}
+		return exitOK
+	}
+	if len(broken) == 0 {
+		fmt.Printf("Password meets the '%s' policy\n", policy.Name)
+		return exitOK
+	}
+	fmt.Printf("Password breaks %d rule(s) of the '%s' policy:\n", len(broken), policy.Name)
+	for _, rule := range broken {
+		fmt.Printf("\t» %s\n", rule)
+	}
+	return exitFail
+}
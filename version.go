@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+
+	pg "github.com/wiremoons/passgen/lib"
+)
+
+// versionJSON is set by '--json' to output the version information as JSON
+var versionJSON bool
+
+// buildDate is the time the application was built. The Go tool chain does
+// not record this, so it is set when building with the Makefile, using:
+// -ldflags "-X main.buildDate=2006-01-02T15:04:05Z"
+var buildDate string
+
+// versionInfo holds the version and build details of the application, and
+// the word list it uses - so the generator and vocabulary that produced a
+// password can be recorded.
+type versionInfo struct {
+	Name         string       `json:"name"`
+	Version      string       `json:"version"`
+	Revision     string       `json:"revision,omitempty"`
+	RevisionTime string       `json:"revision_time,omitempty"`
+	Modified     bool         `json:"modified"`
+	BuildDate    string       `json:"build_date,omitempty"`
+	GoVersion    string       `json:"go_version"`
+	Compiler     string       `json:"compiler"`
+	Platform     string       `json:"platform"`
+	Wordlist     wordlistInfo `json:"wordlist"`
+}
+
+// wordlistInfo describes the three letter word list in use.
+type wordlistInfo struct {
+	Name   string `json:"name"`
+	Words  int    `json:"words"`
+	SHA256 string `json:"sha256"`
+}
+
+// versionFlags adds the flags used by the 'version' sub command.
+func versionFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&versionJSON, "json", false, "\tUSE: '--json' output the version information as JSON")
+}
+
+// getVersionInfo collects the version information. The source revision,
+// its time and whether the source had been modified are recorded by the Go
+// tool chain when the application is built from a git checkout.
+func getVersionInfo() versionInfo {
+	info := versionInfo{
+		Name:      appname,
+		Version:   appversion,
+		GoVersion: runtime.Version(),
+		Compiler:  runtime.Compiler,
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		BuildDate: buildDate,
+		Wordlist: wordlistInfo{
+			Name:   pg.WordlistName,
+			Words:  len(pg.Passmap),
+			SHA256: pg.WordlistFingerprint(),
+		},
+	}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range build.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				info.Revision = s.Value
+			case "vcs.time":
+				info.RevisionTime = s.Value
+			case "vcs.modified":
+				info.Modified = s.Value == "true"
+			}
+		}
+	}
+	return info
+}
+
+// runVersion handles the 'version' sub command.
+func runVersion(fs *flag.FlagSet) int {
+	info := getVersionInfo()
+	if versionJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(info); err != nil {
+			return fail(ioErrorf("unable to output the version information: %s", err))
+		}
+		return exitOK
+	}
+	// print app name called and version information
+	fmt.Printf("\n Running %s version %s\n", appname, appversion)
+	fmt.Printf(" Built with Go Complier '%s' on Golang version '%s' for '%s'\n", info.Compiler, info.GoVersion, info.Platform)
+	if info.Revision != "" {
+		modified := ""
+		if info.Modified {
+			modified = " - with uncommitted changes"
+		}
+		fmt.Printf(" - Source revision: %s committed %s%s\n", info.Revision, info.RevisionTime, modified)
+	} else {
+		fmt.Printf(" - Source revision: unknown - not built from a git checkout\n")
+	}
+	if info.BuildDate != "" {
+		fmt.Printf(" - Build date: %s\n", info.BuildDate)
+	}
+	fmt.Printf(" - Word list: %s - %d words, SHA-256 %s\n", info.Wordlist.Name, info.Wordlist.Words, info.Wordlist.SHA256)
+	fmt.Printf(" - Author's web site: http://www.wiremoons.com/\n")
+	fmt.Printf(" - Source code for %s: https://github.com/wiremoons/passgen/\n", appname)
+	fmt.Printf("\nAll is well\n")
+	return exitOK
+}
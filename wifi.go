@@ -0,0 +1,145 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	pg "github.com/wiremoons/passgen/lib"
+)
+
+// wifiSSID and wifiHidden describe the network for the 'wifi' sub command,
+// and wifiSeparator is put between the words of the passphrase
+var wifiSSID string
+var wifiHidden bool
+var wifiSeparator string
+
+// wifiPolicy is the policy a Wi-Fi passphrase must meet
+const wifiPolicy = "wifi-wpa2"
+
+// the longest network name allowed, in bytes
+const maxSSID = 32
+
+// wifiFlags adds the flags used by the 'wifi' sub command.
+func wifiFlags(fs *flag.FlagSet) {
+	fs.StringVar(&wifiSSID, "ssid", "", "\tUSE: '--ssid NAME' the name of the Wi-Fi network - required")
+	fs.BoolVar(&wifiHidden, "hidden", false, "\tUSE: '--hidden' the network does not broadcast its name [DEFAULT: broadcast]")
+	fs.IntVar(&numwords, "w", 4, "\tUSE: '-w #' where # is the number of three letter words to use - the passphrase must be 8 to 63 characters [DEFAULT: 4]")
+	fs.BoolVar(&passcase, "c", false, "\tUSE: '-c' mixed case passphrase [DEFAULT: lowercase]")
+	fs.StringVar(&wifiSeparator, "separator", "-", "\tUSE: '--separator TEXT' put TEXT between the words of the passphrase [DEFAULT: -]")
+	fs.BoolVar(&quiet, "q", false, "\tUSE: '-q' output just the join string - no QR code or other screen output [DEFAULT: additional info output]")
+	fs.BoolVar(&phonetic, "phonetic", false, "\tUSE: '--phonetic' spell out the passphrase with the NATO phonetic alphabet, marking upper case, digits and symbols")
+	fs.StringVar(&qrFile, "qr-file", "", "\tUSE: '--qr-file PATH' also write the QR code to the PNG or SVG file PATH, readable only by you")
+	fs.BoolVar(&outForce, "force", false, "\tUSE: '--force' with '--qr-file' replace a file that already exists [DEFAULT: refuse]")
+	nonTTYFlags(fs)
+	colourFlags(fs)
+}
+
+// runWifi handles the 'wifi' sub command, which generates a passphrase for
+// a WPA2 or WPA3 personal Wi-Fi network, and outputs it with the join
+// string phones read from a QR code: WIFI:T:WPA;S:<ssid>;P:<passphrase>;;
+// The QR code is shown in the terminal too, unless '-q' is used. The exit
+// code to use is returned.
+func runWifi(fs *flag.FlagSet) int {
+	if wifiSSID == "" {
+		return fail(usageErrorf("the name of the network is needed - use '--ssid NAME'"))
+	}
+	if len(wifiSSID) > maxSSID {
+		return fail(usageErrorf("the network name can be at most %d bytes long", maxSSID))
+	}
+	if err := checkRange("w", numwords, 1, maxWords); err != nil {
+		return fail(err)
+	}
+	if err := checkQRFile(); err != nil {
+		return fail(err)
+	}
+	if err := checkColour(); err != nil {
+		return fail(err)
+	}
+	policy, err := loadPolicy(wifiPolicy)
+	if err != nil {
+		return fail(err)
+	}
+	passphrase, err := wifiPassphrase(policy)
+	if err != nil {
+		return fail(err)
+	}
+	if err := guardStdout(); err != nil {
+		return fail(err)
+	}
+
+	join := wifiJoinString(wifiSSID, passphrase, wifiHidden)
+	if quiet {
+		fmt.Printf("%s\n", join)
+		if err := outputQR(join); err != nil {
+			return fail(err)
+		}
+		return exitOK
+	}
+
+	title := "THREE WORD - WI-FI PASSPHRASE"
+	fmt.Printf("\n\t\t\t%s\n\t\t\t%s\n", title, strings.Repeat("¯", len(title)))
+	fmt.Printf("» Network name: %s\n", wifiSSID)
+	if wifiHidden {
+		fmt.Printf("\t» The network is hidden, so phones are told to look for it\n")
+	}
+	fmt.Printf("» Security: WPA2/WPA3 personal (%s policy)\n", policy.Name)
+	fmt.Printf("» Passphrase: %s\n", paint(passphrase))
+	if phonetic {
+		fmt.Printf("\t» %s\n", pg.Phonetic(passphrase))
+	}
+	fmt.Printf("» Join string: %s\n", join)
+	fmt.Printf("» Scan the QR code below with a phone camera to join the network:\n\n")
+	qrShow = true
+	if err := outputQR(join); err != nil {
+		return fail(err)
+	}
+	fmt.Printf("\nAll is well\n")
+	return exitOK
+}
+
+// wifiPassphrase returns a passphrase of three letter words joined by the
+// separator, that meets the Wi-Fi policy.
+func wifiPassphrase(policy pg.Policy) (string, error) {
+	var problems []string
+	for i := 0; i < maxAttempts; i++ {
+		words := strings.Fields(getPassword(numwords))
+		if passcase {
+			for w := range words {
+				words[w] = mixedPassword(words[w])
+			}
+		}
+		passphrase := strings.Join(words, wifiSeparator)
+		if problems = policy.Check(passphrase); len(problems) > 0 {
+			continue
+		}
+		found, err := breached(passphrase)
+		if err != nil {
+			return "", err
+		}
+		if !found {
+			return passphrase, nil
+		}
+	}
+	if len(problems) > 0 {
+		return "", policyErrorf("the passphrase does not meet the %s policy: %s - change '-w' or '--separator'", policy.Name, strings.Join(problems, ", "))
+	}
+	return "", policyErrorf("no passphrase meeting the %s policy found after %d attempts", policy.Name, maxAttempts)
+}
+
+// wifiEscape escapes the characters with a special meaning in a Wi-Fi join
+// string - backslash, semicolon, comma, colon and double quote - with a
+// backslash.
+func wifiEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `;`, `\;`, `,`, `\,`, `:`, `\:`, `"`, `\"`).Replace(s)
+}
+
+// wifiJoinString returns the string read by phones from a QR code to join a
+// WPA network, in the format first used by the ZXing barcode scanner.
+func wifiJoinString(ssid, passphrase string, hidden bool) string {
+	join := "WIFI:T:WPA;S:" + wifiEscape(ssid) + ";P:" + wifiEscape(passphrase) + ";"
+	if hidden {
+		join += "H:true;"
+	}
+	return join + ";"
+}